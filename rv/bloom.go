@@ -0,0 +1,209 @@
+package rv
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"strconv"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/redis/rueidis"
+)
+
+// maxBloomBits is the largest bitmap a Redis string can hold (512 MiB).
+const maxBloomBits = 1 << 32
+
+// bloomAddScript sets the bits of every item and returns how many items had at least one unset bit, i.e.
+// were certainly not in the filter before. ARGV holds the TTL in milliseconds, the bits per item and then the
+// bit offsets of every item in turn.
+var bloomAddScript = rueidis.NewLuaScript(`
+local hashes = tonumber(ARGV[2])
+local added = 0
+for i = 3, #ARGV, hashes do
+	local new = 0
+	for j = i, i + hashes - 1 do
+		if redis.call('SETBIT', KEYS[1], ARGV[j], 1) == 0 then
+			new = 1
+		end
+	end
+	added = added + new
+end
+local ttl = tonumber(ARGV[1])
+if ttl > 0 and redis.call('PTTL', KEYS[1]) == -1 then
+	redis.call('PEXPIRE', KEYS[1], ttl)
+end
+return added
+`)
+
+// bloomContainsScript returns 1 or 0 for every item, depending on whether all of its bits are set.
+var bloomContainsScript = rueidis.NewLuaScript(`
+local hashes = tonumber(ARGV[1])
+local result = {}
+for i = 2, #ARGV, hashes do
+	local found = 1
+	for j = i, i + hashes - 1 do
+		if redis.call('GETBIT', KEYS[1], ARGV[j]) == 0 then
+			found = 0
+			break
+		end
+	end
+	result[#result + 1] = found
+end
+return result
+`)
+
+// BloomFilter is a namespaced set of Bloom filters over plain Redis bitmaps, so it works without the
+// RedisBloom module. A filter answers whether an item was possibly added, or certainly not, using a fixed
+// amount of memory. Items are encoded with CBOR, the same encoding Value uses, before hashing.
+type BloomFilter[K any] struct {
+	client rueidis.Client
+	key    string
+	bits   uint64
+	hashes int
+
+	config valueConfig
+}
+
+// NewBloomFilter instantiates a BloomFilter helper for the provided key prefix, sized so that a filter holding
+// capacity items reports false positives at about falsePositiveRate. Filters grow past that rate once they hold
+// more items. WithDefaultExpiration sets a TTL on the first write to a filter, and WithHashTag applies as it
+// does to Value.
+func NewBloomFilter[K any](client rueidis.Client, key string, capacity uint64, falsePositiveRate float64, options ...Option) *BloomFilter[K] {
+	bits, hashes := bloomSize(capacity, falsePositiveRate)
+	b := &BloomFilter[K]{client: client, key: key, bits: bits, hashes: hashes}
+
+	for _, opt := range options {
+		opt(&b.config)
+	}
+
+	if b.config.hashTag {
+		b.key = "{" + key + "}"
+	}
+
+	return b
+}
+
+// bloomSize returns the optimal number of bits and hash functions for capacity items at the given false
+// positive rate: m = -n ln(p) / ln(2)^2 and k = m/n ln(2).
+func bloomSize(capacity uint64, falsePositiveRate float64) (uint64, int) {
+	n := float64(max(capacity, 1))
+	p := min(max(falsePositiveRate, 1e-12), 0.5)
+
+	m := math.Ceil(-n * math.Log(p) / (math.Ln2 * math.Ln2))
+	bits := uint64(min(max(m, 8), maxBloomBits))
+	hashes := max(int(math.Round(float64(bits)/n*math.Ln2)), 1)
+
+	return bits, hashes
+}
+
+// Add inserts the items into the named filter and returns how many of them were certainly not in it before.
+func (b *BloomFilter[K]) Add(ctx context.Context, name string, items ...K) (int64, error) {
+	if len(items) == 0 {
+		return 0, nil
+	}
+
+	var ttl int64
+	if b.config.expires != nil {
+		ttl = b.config.expires.Milliseconds()
+	}
+
+	args := []string{strconv.FormatInt(ttl, 10), strconv.Itoa(b.hashes)}
+	args, err := b.appendOffsets(args, items)
+	if err != nil {
+		return 0, err
+	}
+
+	added, err := bloomAddScript.Exec(ctx, b.client, []string{b.namespacedKey(name)}, args).AsInt64()
+	if err != nil {
+		return 0, fmt.Errorf("failed to add to bloom filter: %w", err)
+	}
+
+	return added, nil
+}
+
+// Contains reports whether the item was possibly added to the named filter. False means it certainly was not.
+func (b *BloomFilter[K]) Contains(ctx context.Context, name string, item K) (bool, error) {
+	found, err := b.ContainsMany(ctx, name, item)
+	if err != nil {
+		return false, err
+	}
+
+	return found[0], nil
+}
+
+// ContainsMany is Contains for several items in a single round trip, returning one result per item.
+func (b *BloomFilter[K]) ContainsMany(ctx context.Context, name string, items ...K) ([]bool, error) {
+	if len(items) == 0 {
+		return nil, nil
+	}
+
+	args, err := b.appendOffsets([]string{strconv.Itoa(b.hashes)}, items)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := bloomContainsScript.Exec(ctx, b.client, []string{b.namespacedKey(name)}, args).AsIntSlice()
+	if err != nil {
+		return nil, fmt.Errorf("failed to query bloom filter: %w", err)
+	}
+	if len(resp) != len(items) {
+		return nil, errors.New("failed to query bloom filter: unexpected reply length")
+	}
+
+	found := make([]bool, len(resp))
+	for i, v := range resp {
+		found[i] = v == 1
+	}
+
+	return found, nil
+}
+
+// Delete removes the named filter.
+func (b *BloomFilter[K]) Delete(ctx context.Context, name string) error {
+	if err := b.client.Do(ctx, b.client.B().Del().Key(b.namespacedKey(name)).Build()).Error(); err != nil {
+		return fmt.Errorf("failed to delete bloom filter: %w", err)
+	}
+
+	return nil
+}
+
+func (b *BloomFilter[K]) namespacedKey(name string) string {
+	return b.key + ":" + name
+}
+
+// appendOffsets appends the bit offsets of every item to args.
+func (b *BloomFilter[K]) appendOffsets(args []string, items []K) ([]string, error) {
+	for _, item := range items {
+		encoded, err := cbor.Marshal(item)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode item: %w", err)
+		}
+
+		for _, offset := range bloomOffsets(encoded, b.bits, b.hashes) {
+			args = append(args, strconv.FormatUint(offset, 10))
+		}
+	}
+
+	return args, nil
+}
+
+// bloomOffsets derives the bit offsets of an encoded item by double hashing the two halves of its FNV-128a
+// hash, which keeps filters readable across processes and releases.
+func bloomOffsets(encoded []byte, bits uint64, hashes int) []uint64 {
+	h := fnv.New128a()
+	h.Write(encoded)
+	sum := h.Sum(nil)
+
+	h1 := binary.BigEndian.Uint64(sum[:8])
+	h2 := binary.BigEndian.Uint64(sum[8:]) | 1 // never zero, so the offsets do not all coincide
+
+	offsets := make([]uint64, hashes)
+	for i := range offsets {
+		offsets[i] = (h1 + uint64(i)*h2) % bits
+	}
+
+	return offsets
+}
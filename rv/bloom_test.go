@@ -0,0 +1,95 @@
+package rv
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	rueidismock "github.com/redis/rueidis/mock"
+	"go.uber.org/mock/gomock"
+)
+
+func TestBloomSize(t *testing.T) {
+	t.Parallel()
+
+	bits, hashes := bloomSize(1000, 0.01)
+	if bits != 9586 || hashes != 7 {
+		t.Fatalf("expected 9586 bits and 7 hashes, got %d and %d", bits, hashes)
+	}
+
+	if bits, hashes := bloomSize(0, 0); bits < 8 || hashes < 1 {
+		t.Fatalf("expected degenerate parameters to be clamped, got %d and %d", bits, hashes)
+	}
+}
+
+func TestBloomOffsetsAreStable(t *testing.T) {
+	t.Parallel()
+
+	first := bloomOffsets([]byte("user:42"), 9586, 7)
+	second := bloomOffsets([]byte("user:42"), 9586, 7)
+	for i := range first {
+		if first[i] != second[i] || first[i] >= 9586 {
+			t.Fatalf("unexpected offsets %v and %v", first, second)
+		}
+	}
+}
+
+func TestBloomFilterAddAndContains(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	client := rueidismock.NewClient(ctrl)
+	filter := NewBloomFilter[int64](client, "seen", 1000, 0.01, WithDefaultExpiration(time.Hour))
+
+	client.EXPECT().
+		Do(ctx, rueidismock.MatchFn(func(tokens []string) bool {
+			// EVALSHA sha 1 key ttl hashes and 7 offsets for each of the 2 items.
+			return len(tokens) == 4+2+14 && tokens[0] == "EVALSHA" && tokens[3] == "seen:ids" &&
+				tokens[4] == "3600000" && tokens[5] == "7"
+		}, "EVALSHA bloom add")).
+		Return(rueidismock.Result(rueidismock.RedisInt64(2)))
+
+	added, err := filter.Add(ctx, "ids", 1, 2)
+	if err != nil {
+		t.Fatalf("Add returned error: %v", err)
+	}
+	if added != 2 {
+		t.Fatalf("expected 2 new items, got %d", added)
+	}
+
+	client.EXPECT().
+		Do(ctx, rueidismock.MatchFn(func(tokens []string) bool {
+			return len(tokens) == 4+1+14 && tokens[0] == "EVALSHA" && tokens[3] == "seen:ids" && tokens[4] == "7"
+		}, "EVALSHA bloom contains")).
+		Return(rueidismock.Result(rueidismock.RedisArray(rueidismock.RedisInt64(1), rueidismock.RedisInt64(0))))
+
+	found, err := filter.ContainsMany(ctx, "ids", 1, 3)
+	if err != nil {
+		t.Fatalf("ContainsMany returned error: %v", err)
+	}
+	if !found[0] || found[1] {
+		t.Fatalf("expected [true false], got %v", found)
+	}
+}
+
+func TestBloomFilterHonorsHashTag(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	client := rueidismock.NewClient(ctrl)
+	filter := NewBloomFilter[string](client, "seen", 1000, 0.01, WithHashTag())
+
+	client.EXPECT().
+		Do(ctx, rueidismock.Match("DEL", "{seen}:emails")).
+		Return(rueidismock.Result(rueidismock.RedisInt64(1)))
+
+	if err := filter.Delete(ctx, "emails"); err != nil {
+		t.Fatalf("Delete returned error: %v", err)
+	}
+}
@@ -0,0 +1,234 @@
+package rv
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/redis/rueidis"
+)
+
+// ErrCircuitOpen is reported while the circuit breaker configured with WithCircuitBreaker is open. Reads
+// wrap it together with rueidis.Nil, so they are handled like any other cache miss.
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// WithCircuitBreaker stops sending commands to the store after threshold consecutive failures, such as
+// connection errors or timeouts, so callers can degrade to their source of truth instead of failing requests.
+// Error replies from Redis, missing keys and canceled contexts do not count as failures.
+//
+// While the circuit is open, Get reports a miss wrapping both ErrCircuitOpen and rueidis.Nil, unconditional
+// Set calls are skipped, and every other operation fails with an error wrapping ErrCircuitOpen. Once cooldown
+// has elapsed, a single operation is let through as a probe: if it succeeds the circuit closes, otherwise it
+// stays open for another cooldown. Sub-namespaces share the breaker of their parent.
+func WithCircuitBreaker(threshold int, cooldown time.Duration) Option {
+	return func(r *valueConfig) {
+		r.breakerThreshold = threshold
+		r.breakerCooldown = cooldown
+	}
+}
+
+type circuitState int
+
+const (
+	circuitClosed circuitState = iota
+	circuitOpen
+	circuitHalfOpen
+)
+
+type circuitBreaker struct {
+	mu        sync.Mutex
+	state     circuitState
+	failures  int
+	openedAt  time.Time
+	threshold int
+	cooldown  time.Duration
+	now       func() time.Time
+}
+
+// allow reports whether an operation may reach the store. In the half-open state only the probe is allowed.
+func (b *circuitBreaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case circuitOpen:
+		if b.now().Sub(b.openedAt) < b.cooldown {
+			return false
+		}
+		b.state = circuitHalfOpen
+		return true
+	case circuitHalfOpen:
+		return false
+	default:
+		return true
+	}
+}
+
+// record updates the breaker with the outcome of an allowed operation.
+func (b *circuitBreaker) record(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch {
+	case isOutage(err):
+		b.failures++
+		if b.state == circuitHalfOpen || b.failures >= b.threshold {
+			b.state = circuitOpen
+			b.openedAt = b.now()
+		}
+	case errors.Is(err, context.Canceled):
+		if b.state == circuitHalfOpen {
+			b.state = circuitOpen // inconclusive probe, let the next operation probe again
+		}
+	default:
+		b.state = circuitClosed
+		b.failures = 0
+	}
+}
+
+// isOutage reports whether err indicates that the store is unavailable rather than that it replied.
+func isOutage(err error) bool {
+	var redisErr *rueidis.RedisError
+	return err != nil && !errors.As(err, &redisErr) && !errors.Is(err, context.Canceled)
+}
+
+// breakerStore guards a Store with a circuitBreaker.
+type breakerStore struct {
+	store   Store
+	breaker *circuitBreaker
+}
+
+func newBreakerStore(store Store, threshold int, cooldown time.Duration) *breakerStore {
+	return &breakerStore{
+		store:   store,
+		breaker: &circuitBreaker{threshold: max(threshold, 1), cooldown: cooldown, now: time.Now},
+	}
+}
+
+var errCircuitMiss = fmt.Errorf("%w: %w", ErrCircuitOpen, rueidis.Nil)
+
+func (s *breakerStore) Get(ctx context.Context, key string) ([]byte, error) {
+	if !s.breaker.allow() {
+		return nil, errCircuitMiss
+	}
+
+	value, err := s.store.Get(ctx, key)
+	s.breaker.record(err)
+
+	return value, err
+}
+
+func (s *breakerStore) GetTTL(ctx context.Context, key string) ([]byte, time.Duration, error) {
+	if !s.breaker.allow() {
+		return nil, 0, errCircuitMiss
+	}
+
+	value, ttl, err := s.store.GetTTL(ctx, key)
+	s.breaker.record(err)
+
+	return value, ttl, err
+}
+
+func (s *breakerStore) MGet(ctx context.Context, keys []string) (map[string][]byte, error) {
+	if !s.breaker.allow() {
+		return nil, ErrCircuitOpen
+	}
+
+	values, err := s.store.MGet(ctx, keys)
+	s.breaker.record(err)
+
+	return values, err
+}
+
+func (s *breakerStore) Set(ctx context.Context, key string, value []byte, args SetArgs) error {
+	if !s.breaker.allow() {
+		if args.NX || args.XX {
+			return ErrCircuitOpen
+		}
+		return nil
+	}
+
+	err := s.store.Set(ctx, key, value, args)
+	s.breaker.record(err)
+
+	return err
+}
+
+func (s *breakerStore) Delete(ctx context.Context, keys ...string) (int64, error) {
+	if !s.breaker.allow() {
+		return 0, ErrCircuitOpen
+	}
+
+	n, err := s.store.Delete(ctx, keys...)
+	s.breaker.record(err)
+
+	return n, err
+}
+
+func (s *breakerStore) Unlink(ctx context.Context, keys ...string) (int64, error) {
+	if !s.breaker.allow() {
+		return 0, ErrCircuitOpen
+	}
+
+	n, err := s.store.Unlink(ctx, keys...)
+	s.breaker.record(err)
+
+	return n, err
+}
+
+func (s *breakerStore) Expire(ctx context.Context, ttl time.Duration, keys ...string) (int64, error) {
+	if !s.breaker.allow() {
+		return 0, ErrCircuitOpen
+	}
+
+	n, err := s.store.Expire(ctx, ttl, keys...)
+	s.breaker.record(err)
+
+	return n, err
+}
+
+func (s *breakerStore) TTL(ctx context.Context, key string) (time.Duration, error) {
+	if !s.breaker.allow() {
+		return 0, ErrCircuitOpen
+	}
+
+	ttl, err := s.store.TTL(ctx, key)
+	s.breaker.record(err)
+
+	return ttl, err
+}
+
+func (s *breakerStore) TTLs(ctx context.Context, keys []string) (map[string]time.Duration, error) {
+	if !s.breaker.allow() {
+		return nil, ErrCircuitOpen
+	}
+
+	ttls, err := s.store.TTLs(ctx, keys)
+	s.breaker.record(err)
+
+	return ttls, err
+}
+
+func (s *breakerStore) Scan(ctx context.Context, cursor uint64, match string, count int64) ([]string, uint64, error) {
+	if !s.breaker.allow() {
+		return nil, 0, ErrCircuitOpen
+	}
+
+	keys, next, err := s.store.Scan(ctx, cursor, match, count)
+	s.breaker.record(err)
+
+	return keys, next, err
+}
+
+func (s *breakerStore) CompareAndSwap(ctx context.Context, key string, old, value []byte, args SetArgs) (bool, error) {
+	if !s.breaker.allow() {
+		return false, ErrCircuitOpen
+	}
+
+	swapped, err := s.store.CompareAndSwap(ctx, key, old, value, args)
+	s.breaker.record(err)
+
+	return swapped, err
+}
@@ -0,0 +1,108 @@
+package rv
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/redis/rueidis"
+)
+
+// flakyStore fails every operation with err while it is set and counts the operations that reached it.
+type flakyStore struct {
+	*MemoryStore
+	err   error
+	calls int
+}
+
+func (s *flakyStore) Get(ctx context.Context, key string) ([]byte, error) {
+	s.calls++
+	if s.err != nil {
+		return nil, s.err
+	}
+	return s.MemoryStore.Get(ctx, key)
+}
+
+func (s *flakyStore) Set(ctx context.Context, key string, value []byte, args SetArgs) error {
+	s.calls++
+	if s.err != nil {
+		return s.err
+	}
+	return s.MemoryStore.Set(ctx, key, value, args)
+}
+
+func TestValueCircuitBreakerTripsAndRecovers(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	store := &flakyStore{MemoryStore: NewMemoryStore(), err: errors.New("dial tcp: connection refused")}
+	value := NewValue[testPayload](nil, nil, "guarded", WithStore(store), WithCircuitBreaker(2, time.Minute))
+
+	now := time.Now()
+	breaker := value.store.(*breakerStore).breaker
+	breaker.now = func() time.Time { return now }
+
+	for range 2 {
+		if _, err := value.Get(ctx, "key"); err == nil || errors.Is(err, ErrCircuitOpen) {
+			t.Fatalf("expected the store error before tripping, got %v", err)
+		}
+	}
+
+	_, err := value.Get(ctx, "key")
+	if !errors.Is(err, ErrCircuitOpen) || !errors.Is(err, rueidis.Nil) {
+		t.Fatalf("expected an open circuit miss, got %v", err)
+	}
+	if err := value.Set(ctx, "key", &testPayload{Message: "skipped"}); err != nil {
+		t.Fatalf("expected Set to be skipped while open, got %v", err)
+	}
+	if err := value.Set(ctx, "key", &testPayload{Message: "skipped"}, SetNX(true)); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected conditional Set to fail while open, got %v", err)
+	}
+	if store.calls != 2 {
+		t.Fatalf("expected no operations to reach the store while open, got %d", store.calls)
+	}
+
+	// A failed probe keeps the circuit open for another cooldown.
+	now = now.Add(time.Minute)
+	if _, err := value.Get(ctx, "key"); errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected the probe to reach the store, got %v", err)
+	}
+	if _, err := value.Get(ctx, "key"); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected the circuit to reopen after a failed probe, got %v", err)
+	}
+
+	now = now.Add(time.Minute)
+	store.err = nil
+	if err := value.Set(ctx, "key", &testPayload{Message: "recovered"}); err != nil {
+		t.Fatalf("expected the probe to succeed, got %v", err)
+	}
+
+	got, err := value.Get(ctx, "key")
+	if err != nil || got.Message != "recovered" {
+		t.Fatalf("expected the circuit to close, got %v, %v", got, err)
+	}
+}
+
+func TestIsOutage(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{err: nil, want: false},
+		{err: rueidis.Nil, want: false},
+		{err: fmt.Errorf("failed to get value: %w", rueidis.Nil), want: false},
+		{err: context.Canceled, want: false},
+		{err: context.DeadlineExceeded, want: true},
+		{err: errors.New("connection reset by peer"), want: true},
+	}
+
+	for _, tt := range tests {
+		if got := isOutage(tt.err); got != tt.want {
+			t.Errorf("isOutage(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,313 @@
+package rv
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/redis/rueidis"
+)
+
+// manifestTag and chunkTag are the CBOR tags marking the manifest and the chunks of a chunked value,
+// encoding as the fixed three-byte prefixes manifestPrefix and chunkPrefix.
+const (
+	manifestTag = 0x7277
+	chunkTag    = 0x7278
+)
+
+var (
+	manifestPrefix = []byte{0xd9, 0x72, 0x77}
+	chunkPrefix    = []byte{0xd9, 0x72, 0x78}
+)
+
+// manifest is stored under the key of a chunked value and lists its chunks. Every write uses a new generation,
+// so replacing the manifest switches readers to the new chunks atomically.
+type manifest struct {
+	Generation string `cbor:"0,keyasint"`
+	Chunks     int    `cbor:"1,keyasint"`
+	Size       int    `cbor:"2,keyasint"`
+}
+
+// ErrChunkingUnsupported is returned by operations that cannot manage the chunks of a Value configured with
+// WithChunking, such as TxSet and TxDelete.
+var ErrChunkingUnsupported = errors.New("operation does not support chunked values")
+
+// PayloadTooLargeError is returned by Set when the encoded value exceeds the limit configured with
+// WithMaxPayloadSize.
+type PayloadTooLargeError struct {
+	Key   string
+	Size  int
+	Limit int
+}
+
+func (e *PayloadTooLargeError) Error() string {
+	return fmt.Sprintf("payload of %d bytes for key %q exceeds the limit of %d bytes", e.Size, e.Key, e.Limit)
+}
+
+// WithMaxPayloadSize rejects Set calls whose encoded value is larger than limit bytes with a
+// *PayloadTooLargeError. With WithChunking, the limit applies to the whole value before it is split.
+func WithMaxPayloadSize(limit int) Option {
+	return func(r *valueConfig) {
+		r.maxPayloadSize = limit
+	}
+}
+
+// WithChunking splits encoded values larger than chunkSize bytes across several keys next to the value's key,
+// stored under the key itself as a manifest that Get uses to reassemble the value. Replacing a value swaps the
+// manifest with a compare-and-swap, so readers see either the old or the new value, and the chunks of the
+// replaced value are removed afterward. Chunks share the TTL of the value and live at
+// "prefix~chunk:key:generation:index", outside the namespace, so they are co-located with the key on Redis Cluster
+// when it or the namespace carries a hash tag.
+//
+// Get and Scan reassemble chunked values regardless of this option, while Set, Delete, Expire, DeleteMatching
+// and ExpireMatching only manage chunks when it is enabled. TxSet and TxDelete return ErrChunkingUnsupported
+// with this option, and scripts operate on the raw keys and do not support chunked values.
+func WithChunking(chunkSize int) Option {
+	return func(r *valueConfig) {
+		r.chunkSize = chunkSize
+	}
+}
+
+// checkPayload enforces WithMaxPayloadSize.
+func (r *Value[T]) checkPayload(key string, encoded []byte) error {
+	if r.config.maxPayloadSize > 0 && len(encoded) > r.config.maxPayloadSize {
+		return &PayloadTooLargeError{Key: key, Size: len(encoded), Limit: r.config.maxPayloadSize}
+	}
+
+	return nil
+}
+
+// setChunked stores the encoding under rawKey, splitting it into chunks if it exceeds the chunk size, and
+// removes the chunks of the value it replaced.
+func (r *Value[T]) setChunked(ctx context.Context, rawKey string, encoded []byte, args SetArgs) error {
+	value := encoded
+	var (
+		chunks []string
+		err    error
+	)
+	if len(encoded) > r.config.chunkSize {
+		if value, chunks, err = r.writeChunks(ctx, rawKey, encoded, args); err != nil {
+			return err
+		}
+	}
+
+	previous, err := r.replaceChunked(ctx, rawKey, value, args)
+	if err != nil {
+		if len(chunks) > 0 {
+			_, _ = r.store.Unlink(ctx, chunks...)
+		}
+		return err
+	}
+
+	if stale := r.chunkKeys(rawKey, previous); len(stale) > 0 {
+		// The value is already replaced, so a failure only leaves the stale chunks to their TTL.
+		_, _ = r.store.Unlink(ctx, stale...)
+	}
+
+	return nil
+}
+
+// replaceChunked stores value under rawKey and returns the value it replaced. The write only succeeds if rawKey
+// still holds what was read, so the chunks of a value replaced concurrently by another writer are never missed.
+func (r *Value[T]) replaceChunked(ctx context.Context, rawKey string, value []byte, args SetArgs) ([]byte, error) {
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		previous, err := r.store.Get(ctx, rawKey)
+		if err != nil && !errors.Is(err, rueidis.Nil) {
+			return nil, err
+		}
+		exists := err == nil
+		if (args.NX && exists) || (args.XX && !exists) {
+			return nil, rueidis.Nil
+		}
+
+		if !exists {
+			create := args
+			create.NX, create.XX = true, false
+
+			err := r.store.Set(ctx, rawKey, value, create)
+			if errors.Is(err, rueidis.Nil) {
+				continue // created concurrently
+			}
+			return nil, err
+		}
+
+		swapped, err := r.store.CompareAndSwap(ctx, rawKey, previous, value, args)
+		if err != nil {
+			return nil, err
+		}
+		if swapped {
+			return previous, nil
+		}
+	}
+}
+
+// writeChunks stores the chunks of a new generation and returns its encoded manifest and chunk keys.
+func (r *Value[T]) writeChunks(ctx context.Context, rawKey string, encoded []byte, args SetArgs) ([]byte, []string, error) {
+	ttl := args.TTL
+	if args.KeepTTL {
+		// Chunks are new keys, so they inherit the remaining TTL of the value explicitly.
+		if remaining, err := r.store.TTL(ctx, rawKey); err == nil && remaining > 0 {
+			ttl = remaining
+		}
+	}
+
+	m := manifest{
+		Generation: rand.Text(),
+		Chunks:     (len(encoded) + r.config.chunkSize - 1) / r.config.chunkSize,
+		Size:       len(encoded),
+	}
+	keys := m.keys(r.internalKey("chunk", rawKey))
+
+	for i, key := range keys {
+		end := min((i+1)*r.config.chunkSize, len(encoded))
+
+		chunk, err := cbor.Marshal(cbor.Tag{Number: chunkTag, Content: encoded[i*r.config.chunkSize : end]})
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to encode chunk: %w", err)
+		}
+
+		if err := r.store.Set(ctx, key, chunk, SetArgs{TTL: ttl}); err != nil {
+			_, _ = r.store.Unlink(ctx, keys[:i]...)
+			return nil, nil, fmt.Errorf("failed to write chunk %d: %w", i, err)
+		}
+	}
+
+	encodedManifest, err := cbor.Marshal(cbor.Tag{Number: manifestTag, Content: m})
+	if err != nil {
+		_, _ = r.store.Unlink(ctx, keys...)
+		return nil, nil, fmt.Errorf("failed to encode chunk manifest: %w", err)
+	}
+
+	return encodedManifest, keys, nil
+}
+
+// deleteChunked removes rawKey together with its chunks.
+func (r *Value[T]) deleteChunked(ctx context.Context, rawKey string) error {
+	previous, err := r.store.Get(ctx, rawKey)
+	if err != nil && !errors.Is(err, rueidis.Nil) {
+		return err
+	}
+
+	_, err = r.store.Delete(ctx, append([]string{rawKey}, r.chunkKeys(rawKey, previous)...)...)
+	return err
+}
+
+// matchedChunks returns the chunk keys of the manifests among keys that are not in keys themselves, so that
+// DeleteMatching and ExpireMatching also reach chunks their pattern does not match. It returns nil unless the
+// Value uses WithChunking.
+func (r *Value[T]) matchedChunks(ctx context.Context, keys []string) ([]string, error) {
+	if r.config.chunkSize <= 0 {
+		return nil, nil
+	}
+
+	batch, err := r.store.MGet(ctx, keys)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read chunk manifests: %w", err)
+	}
+
+	matched := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		matched[key] = true
+	}
+
+	var chunks []string
+	for _, key := range keys {
+		for _, chunk := range r.chunkKeys(key, batch[key]) {
+			if !matched[chunk] {
+				chunks = append(chunks, chunk)
+			}
+		}
+	}
+
+	return chunks, nil
+}
+
+// resolveChunks returns data unchanged unless it is a manifest, in which case the chunks are loaded and
+// reassembled. If the chunks were replaced while reading, the manifest is read once more. Chunks that are
+// missing, e.g. because they expired, are reported with an error wrapping rueidis.Nil.
+func (r *Value[T]) resolveChunks(ctx context.Context, rawKey string, data []byte) ([]byte, error) {
+	for attempt := 0; ; attempt++ {
+		if !bytes.HasPrefix(data, manifestPrefix) {
+			return data, nil
+		}
+
+		assembled, err := r.readChunks(ctx, rawKey, data)
+		if !errors.Is(err, rueidis.Nil) || attempt > 0 {
+			return assembled, err
+		}
+
+		if data, err = r.store.Get(ctx, rawKey); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (r *Value[T]) readChunks(ctx context.Context, rawKey string, data []byte) ([]byte, error) {
+	var m manifest
+	if err := cbor.Unmarshal(data[len(manifestPrefix):], &m); err != nil {
+		return nil, fmt.Errorf("failed to decode chunk manifest: %w", err)
+	}
+
+	keys := m.keys(r.internalKey("chunk", rawKey))
+	batch, err := r.store.MGet(ctx, keys)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read chunks: %w", err)
+	}
+
+	assembled := make([]byte, 0, m.Size)
+	for i, key := range keys {
+		chunk, ok := batch[key]
+		if !ok {
+			return nil, fmt.Errorf("chunk %d of %d is missing: %w", i, m.Chunks, rueidis.Nil)
+		}
+
+		var content []byte
+		if !isChunk(chunk) || cbor.Unmarshal(chunk[len(chunkPrefix):], &content) != nil {
+			return nil, fmt.Errorf("chunk %d of %d is corrupted", i, m.Chunks)
+		}
+		assembled = append(assembled, content...)
+	}
+
+	if len(assembled) != m.Size {
+		return nil, fmt.Errorf("reassembled %d bytes, manifest declares %d", len(assembled), m.Size)
+	}
+
+	return assembled, nil
+}
+
+// isChunk reports whether data is a chunk of a chunked value rather than a value itself.
+func isChunk(data []byte) bool {
+	return bytes.HasPrefix(data, chunkPrefix)
+}
+
+// chunkKeys returns the chunk keys referenced by data if it is the manifest stored under rawKey.
+func (r *Value[T]) chunkKeys(rawKey string, data []byte) []string {
+	if !bytes.HasPrefix(data, manifestPrefix) {
+		return nil
+	}
+
+	var m manifest
+	if err := cbor.Unmarshal(data[len(manifestPrefix):], &m); err != nil {
+		return nil
+	}
+
+	return m.keys(r.internalKey("chunk", rawKey))
+}
+
+// keys returns the chunk keys of the manifest below base, the internal chunk key of the value.
+func (m manifest) keys(base string) []string {
+	keys := make([]string, m.Chunks)
+	for i := range keys {
+		keys[i] = base + ":" + m.Generation + ":" + strconv.Itoa(i)
+	}
+
+	return keys
+}
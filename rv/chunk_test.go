@@ -0,0 +1,151 @@
+package rv
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/redis/rueidis"
+)
+
+func TestValueSetRejectsOversizedPayload(t *testing.T) {
+	t.Parallel()
+
+	store := NewMemoryStore()
+	value := NewValue[testPayload](nil, nil, "limited", WithStore(store), WithMaxPayloadSize(64))
+
+	err := value.Set(context.Background(), "big", &testPayload{Message: strings.Repeat("x", 128)})
+
+	var tooLarge *PayloadTooLargeError
+	if !errors.As(err, &tooLarge) {
+		t.Fatalf("expected PayloadTooLargeError, got %v", err)
+	}
+	if tooLarge.Key != "big" || tooLarge.Limit != 64 || tooLarge.Size <= 128 {
+		t.Fatalf("unexpected error details %+v", tooLarge)
+	}
+	if keys := store.Keys(); len(keys) != 0 {
+		t.Fatalf("expected nothing to be stored, got %v", keys)
+	}
+}
+
+func TestValueChunkingRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	store := NewMemoryStore()
+	value := NewValue[testPayload](nil, nil, "blob", WithStore(store), WithChunking(32), WithDefaultExpiration(time.Minute))
+
+	large := &testPayload{Message: strings.Repeat("a", 100)}
+	if err := value.Set(ctx, "doc", large); err != nil {
+		t.Fatalf("Set returned error: %v", err)
+	}
+
+	// The 111 byte encoding is split into 4 chunks next to the manifest.
+	first := store.Keys()
+	if len(first) != 5 {
+		t.Fatalf("expected a manifest and 4 chunks, got %v", first)
+	}
+	for _, key := range first[1:] {
+		if !strings.HasPrefix(key, "blob~chunk:doc:") {
+			t.Fatalf("unexpected chunk key %q", key)
+		}
+		if ttl, _ := store.TTL(ctx, key); ttl != time.Minute {
+			t.Fatalf("expected chunk %q to share the TTL, got %v", key, ttl)
+		}
+	}
+
+	got, err := value.Get(ctx, "doc")
+	if err != nil {
+		t.Fatalf("Get returned error: %v", err)
+	}
+	if got.Message != large.Message {
+		t.Fatalf("unexpected reassembled value %q", got.Message)
+	}
+
+	replacement := &testPayload{Message: strings.Repeat("b", 70)}
+	if err := value.Set(ctx, "doc", replacement); err != nil {
+		t.Fatalf("Set returned error: %v", err)
+	}
+	for _, key := range store.Keys() {
+		for _, old := range first[1:] {
+			if key == old {
+				t.Fatalf("expected stale chunk %q to be removed", key)
+			}
+		}
+	}
+
+	if err := value.Set(ctx, "small", &testPayload{Message: "tiny"}); err != nil {
+		t.Fatalf("Set returned error: %v", err)
+	}
+
+	values, err := value.Scan(ctx, "")
+	if err != nil {
+		t.Fatalf("Scan returned error: %v", err)
+	}
+	if len(values) != 2 || values[0].Message != replacement.Message || values[1].Message != "tiny" {
+		t.Fatalf("expected Scan to skip chunks and reassemble the value, got %v", values)
+	}
+
+	if err := value.Delete(ctx, "doc"); err != nil {
+		t.Fatalf("Delete returned error: %v", err)
+	}
+	if keys := store.Keys(); len(keys) != 1 || keys[0] != "blob:small" {
+		t.Fatalf("expected Delete to remove the chunks, got %v", keys)
+	}
+}
+
+func TestValueChunkingMissingChunkIsMiss(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	store := NewMemoryStore()
+	value := NewValue[testPayload](nil, nil, "blob", WithStore(store), WithChunking(16))
+
+	if err := value.Set(ctx, "doc", &testPayload{Message: strings.Repeat("c", 40)}); err != nil {
+		t.Fatalf("Set returned error: %v", err)
+	}
+
+	keys := store.Keys()
+	if _, err := store.Delete(ctx, keys[len(keys)-1]); err != nil {
+		t.Fatalf("Delete returned error: %v", err)
+	}
+
+	if _, err := value.Get(ctx, "doc"); !errors.Is(err, rueidis.Nil) {
+		t.Fatalf("expected a miss for incomplete chunks, got %v", err)
+	}
+}
+
+func TestValueChunkingMatchingReachesChunks(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	store := NewMemoryStore()
+	value := NewValue[testPayload](nil, nil, "blob", WithStore(store), WithChunking(16))
+
+	for _, key := range []string{"doc-1", "doc-2"} {
+		if err := value.Set(ctx, key, &testPayload{Message: strings.Repeat("d", 40)}); err != nil {
+			t.Fatalf("Set returned error: %v", err)
+		}
+	}
+
+	updated, err := value.ExpireMatching(ctx, "doc-1", time.Minute)
+	if err != nil || updated != 1 {
+		t.Fatalf("expected one value to be updated, got %d, %v", updated, err)
+	}
+	for _, key := range store.Keys() {
+		ttl, _ := store.TTL(ctx, key)
+		if strings.Contains(key, "doc-1") != (ttl == time.Minute) {
+			t.Fatalf("unexpected TTL %v of %q", ttl, key)
+		}
+	}
+
+	removed, err := value.DeleteMatching(ctx, "doc-?")
+	if err != nil || removed != 2 {
+		t.Fatalf("expected two values to be removed, got %d, %v", removed, err)
+	}
+	if keys := store.Keys(); len(keys) != 0 {
+		t.Fatalf("expected the chunks to be removed, got %v", keys)
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/DeltaLaboratory/contrib/rv"
+)
+
+type user struct {
+	Name string
+}
+
+func TestExportRestoreRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	file := filepath.Join(t.TempDir(), "users.rvdump")
+
+	source := rv.NewMemoryStore()
+	users := rv.NewValue[user](nil, nil, "users", rv.WithStore(source))
+	if err := users.Set(ctx, "1", &user{Name: "Ada"}, rv.SetTTL(time.Hour)); err != nil {
+		t.Fatalf("Set returned error: %v", err)
+	}
+	if err := users.Set(ctx, "2", &user{Name: "Grace"}); err != nil {
+		t.Fatalf("Set returned error: %v", err)
+	}
+
+	if err := export(ctx, rv.NewValue[any](nil, nil, "users", rv.WithStore(source)), file, ""); err != nil {
+		t.Fatalf("export returned error: %v", err)
+	}
+
+	target := rv.NewMemoryStore()
+	if err := restore(ctx, rv.NewValue[any](nil, nil, "restored", rv.WithStore(target)), file); err != nil {
+		t.Fatalf("restore returned error: %v", err)
+	}
+
+	restored := rv.NewValue[user](nil, nil, "restored", rv.WithStore(target))
+	for key, name := range map[string]string{"1": "Ada", "2": "Grace"} {
+		got, err := restored.Get(ctx, key)
+		if err != nil || got.Name != name {
+			t.Fatalf("key %s: expected %q, got %v, %v", key, name, got, err)
+		}
+	}
+
+	if ttl, _ := target.TTL(ctx, "restored:1"); ttl <= 0 || ttl > time.Hour {
+		t.Fatalf("expected the TTL to be restored, got %v", ttl)
+	}
+	if ttl, _ := target.TTL(ctx, "restored:2"); ttl >= 0 {
+		t.Fatalf("expected no expiration to be restored, got %v", ttl)
+	}
+}
@@ -0,0 +1,248 @@
+package rv
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/rueidis"
+)
+
+// Counter is a namespaced set of numeric counters backed by Redis' atomic INCR family of commands.
+// Unlike Value, counters are stored as plain Redis numbers so concurrent updates never race.
+type Counter struct {
+	client rueidis.Client
+	key    string
+
+	config valueConfig
+}
+
+// NewCounter instantiates a Counter helper for the provided key prefix.
+// WithDefaultExpiration sets a TTL on the first write to a counter; later writes leave it untouched,
+// so the counter resets once the TTL elapses. Applying a TTL requires Redis 7.0 or newer. WithHashTag applies as
+// it does to Value.
+func NewCounter(client rueidis.Client, key string, options ...Option) *Counter {
+	c := &Counter{key: key, client: client}
+
+	for _, opt := range options {
+		opt(&c.config)
+	}
+
+	if c.config.hashTag {
+		c.key = "{" + key + "}"
+	}
+
+	return c
+}
+
+// Incr increments the counter by one and returns the new value.
+func (c *Counter) Incr(ctx context.Context, key string) (int64, error) {
+	return c.IncrBy(ctx, key, 1)
+}
+
+// Decr decrements the counter by one and returns the new value.
+func (c *Counter) Decr(ctx context.Context, key string) (int64, error) {
+	return c.IncrBy(ctx, key, -1)
+}
+
+// IncrBy increments the counter by delta and returns the new value.
+func (c *Counter) IncrBy(ctx context.Context, key string, delta int64) (int64, error) {
+	resp, err := c.do(ctx, key, c.client.B().Incrby().Key(c.key+":"+key).Increment(delta).Build())
+	if err != nil {
+		return 0, fmt.Errorf("failed to increment counter: %w", err)
+	}
+
+	value, err := resp.AsInt64()
+	if err != nil {
+		return 0, fmt.Errorf("failed to increment counter: %w", err)
+	}
+
+	return value, nil
+}
+
+// IncrByFloat increments the counter by a floating point delta and returns the new value.
+func (c *Counter) IncrByFloat(ctx context.Context, key string, delta float64) (float64, error) {
+	resp, err := c.do(ctx, key, c.client.B().Incrbyfloat().Key(c.key+":"+key).Increment(delta).Build())
+	if err != nil {
+		return 0, fmt.Errorf("failed to increment counter: %w", err)
+	}
+
+	value, err := resp.AsFloat64()
+	if err != nil {
+		return 0, fmt.Errorf("failed to increment counter: %w", err)
+	}
+
+	return value, nil
+}
+
+// Get returns the current value of the counter. Missing counters read as zero.
+func (c *Counter) Get(ctx context.Context, key string) (int64, error) {
+	value, err := c.client.Do(ctx, c.client.B().Get().Key(c.key+":"+key).Build()).AsInt64()
+	if err != nil && !rueidis.IsRedisNil(err) {
+		return 0, fmt.Errorf("failed to get counter: %w", err)
+	}
+
+	return value, nil
+}
+
+// GetFloat returns the current value of a counter updated with IncrByFloat. Missing counters read as zero.
+func (c *Counter) GetFloat(ctx context.Context, key string) (float64, error) {
+	value, err := c.client.Do(ctx, c.client.B().Get().Key(c.key+":"+key).Build()).AsFloat64()
+	if err != nil && !rueidis.IsRedisNil(err) {
+		return 0, fmt.Errorf("failed to get counter: %w", err)
+	}
+
+	return value, nil
+}
+
+// GetAndReset atomically returns the current value of the counter and resets it to zero.
+// The next write after a reset is treated as a first write and re-applies the default TTL.
+func (c *Counter) GetAndReset(ctx context.Context, key string) (int64, error) {
+	value, err := c.client.Do(ctx, c.client.B().Getdel().Key(c.key+":"+key).Build()).AsInt64()
+	if err != nil && !rueidis.IsRedisNil(err) {
+		return 0, fmt.Errorf("failed to reset counter: %w", err)
+	}
+
+	return value, nil
+}
+
+// GetAndResetFloat is the floating point variant of GetAndReset.
+func (c *Counter) GetAndResetFloat(ctx context.Context, key string) (float64, error) {
+	value, err := c.client.Do(ctx, c.client.B().Getdel().Key(c.key+":"+key).Build()).AsFloat64()
+	if err != nil && !rueidis.IsRedisNil(err) {
+		return 0, fmt.Errorf("failed to reset counter: %w", err)
+	}
+
+	return value, nil
+}
+
+// do executes the increment command, pipelining a PEXPIRE NX when a default TTL is configured.
+func (c *Counter) do(ctx context.Context, key string, incr rueidis.Completed) (rueidis.RedisMessage, error) {
+	if c.config.expires == nil {
+		return c.client.Do(ctx, incr).ToMessage()
+	}
+
+	expire := c.client.B().Pexpire().Key(c.key + ":" + key).Milliseconds(c.config.expires.Milliseconds()).Nx().Build()
+
+	resp := c.client.DoMulti(ctx, incr, expire)
+	if err := resp[1].Error(); err != nil {
+		return rueidis.RedisMessage{}, err
+	}
+
+	return resp[0].ToMessage()
+}
+
+// WindowedCounter counts events in fixed time buckets (e.g. per minute or per hour) and sums them over a
+// sliding window. Each bucket is a separate Redis key that expires on its own once it leaves the retention period.
+type WindowedCounter struct {
+	client    rueidis.Client
+	key       string
+	bucket    time.Duration
+	retention time.Duration
+
+	now func() time.Time
+}
+
+// NewWindowedCounter instantiates a WindowedCounter with the given bucket size, which must be a whole number of
+// seconds. Buckets are kept for retention after they close, which bounds the largest window Sum accepts.
+// WithHashTag applies as it does to Value, so the buckets Sum reads together share a Redis Cluster slot.
+func NewWindowedCounter(client rueidis.Client, key string, bucket, retention time.Duration, options ...Option) (*WindowedCounter, error) {
+	if bucket < time.Second || bucket%time.Second != 0 {
+		return nil, fmt.Errorf("bucket %s must be a positive whole number of seconds", bucket)
+	}
+	if retention < 0 {
+		return nil, fmt.Errorf("retention %s must not be negative", retention)
+	}
+
+	var config valueConfig
+	for _, opt := range options {
+		opt(&config)
+	}
+
+	if config.hashTag {
+		key = "{" + key + "}"
+	}
+
+	return &WindowedCounter{
+		client:    client,
+		key:       key,
+		bucket:    bucket,
+		retention: retention,
+		now:       time.Now,
+	}, nil
+}
+
+// Incr increments the current bucket by one and returns the bucket's new value.
+func (c *WindowedCounter) Incr(ctx context.Context, key string) (int64, error) {
+	return c.IncrBy(ctx, key, 1)
+}
+
+// IncrBy increments the current bucket by delta and returns the bucket's new value.
+func (c *WindowedCounter) IncrBy(ctx context.Context, key string, delta int64) (int64, error) {
+	start := c.now().Truncate(c.bucket)
+	bucketKey := c.bucketKey(key, start)
+
+	resp := c.client.DoMulti(ctx,
+		c.client.B().Incrby().Key(bucketKey).Increment(delta).Build(),
+		c.client.B().Expireat().Key(bucketKey).Timestamp(start.Add(c.bucket+c.retention).Unix()).Build(),
+	)
+	if err := resp[1].Error(); err != nil {
+		return 0, fmt.Errorf("failed to increment counter: %w", err)
+	}
+
+	value, err := resp[0].AsInt64()
+	if err != nil {
+		return 0, fmt.Errorf("failed to increment counter: %w", err)
+	}
+
+	return value, nil
+}
+
+// Sum adds up the buckets overlapping the trailing window, including the current bucket.
+func (c *WindowedCounter) Sum(ctx context.Context, key string, window time.Duration) (int64, error) {
+	if window <= 0 {
+		return 0, errors.New("window must be positive")
+	}
+	if window > c.retention+c.bucket {
+		return 0, fmt.Errorf("window %s exceeds counter retention %s", window, c.retention+c.bucket)
+	}
+
+	current := c.now().Truncate(c.bucket)
+	count := int((window + c.bucket - 1) / c.bucket)
+
+	keys := make([]string, count)
+	for i := range keys {
+		keys[i] = c.bucketKey(key, current.Add(-time.Duration(i)*c.bucket))
+	}
+
+	batch, err := rueidis.MGet(c.client, ctx, keys)
+	if err != nil {
+		return 0, fmt.Errorf("failed to load counter buckets: %w", err)
+	}
+
+	var sum int64
+	for _, bucketKey := range keys {
+		msg, ok := batch[bucketKey]
+		if !ok {
+			continue
+		}
+
+		value, err := msg.AsInt64()
+		if err != nil {
+			if rueidis.IsRedisNil(err) {
+				continue // bucket never written or already expired
+			}
+			return 0, fmt.Errorf("failed to load counter bucket %q: %w", bucketKey, err)
+		}
+
+		sum += value
+	}
+
+	return sum, nil
+}
+
+func (c *WindowedCounter) bucketKey(key string, start time.Time) string {
+	return c.key + ":" + key + ":" + strconv.FormatInt(start.Unix(), 10)
+}
@@ -0,0 +1,208 @@
+package rv
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/redis/rueidis"
+	rueidismock "github.com/redis/rueidis/mock"
+	"go.uber.org/mock/gomock"
+)
+
+func TestCounterIncrByWithoutTTL(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	client := rueidismock.NewClient(ctrl)
+	counter := NewCounter(client, "hits")
+
+	client.EXPECT().
+		Do(ctx, rueidismock.Match("INCRBY", "hits:page", "5")).
+		Return(rueidismock.Result(rueidismock.RedisInt64(7)))
+
+	value, err := counter.IncrBy(ctx, "page", 5)
+	if err != nil {
+		t.Fatalf("IncrBy returned error: %v", err)
+	}
+	if value != 7 {
+		t.Fatalf("expected 7, got %d", value)
+	}
+}
+
+func TestCounterAppliesTTLOnFirstWrite(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	client := rueidismock.NewClient(ctrl)
+	counter := NewCounter(client, "quota", WithDefaultExpiration(time.Minute))
+
+	client.EXPECT().
+		DoMulti(ctx,
+			rueidismock.Match("INCRBY", "quota:user", "-1"),
+			rueidismock.Match("PEXPIRE", "quota:user", "60000", "NX"),
+		).
+		Return([]rueidis.RedisResult{
+			rueidismock.Result(rueidismock.RedisInt64(-1)),
+			rueidismock.Result(rueidismock.RedisInt64(1)),
+		})
+
+	value, err := counter.Decr(ctx, "user")
+	if err != nil {
+		t.Fatalf("Decr returned error: %v", err)
+	}
+	if value != -1 {
+		t.Fatalf("expected -1, got %d", value)
+	}
+}
+
+func TestCounterGetAndResetTreatsMissingAsZero(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	client := rueidismock.NewClient(ctrl)
+	counter := NewCounter(client, "metrics")
+
+	client.EXPECT().
+		Do(ctx, rueidismock.Match("GETDEL", "metrics:requests")).
+		Return(rueidismock.Result(rueidismock.RedisNil()))
+
+	value, err := counter.GetAndReset(ctx, "requests")
+	if err != nil {
+		t.Fatalf("GetAndReset returned error: %v", err)
+	}
+	if value != 0 {
+		t.Fatalf("expected 0, got %d", value)
+	}
+}
+
+func TestWindowedCounterSumsBuckets(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	client := rueidismock.NewClient(ctrl)
+	counter, err := NewWindowedCounter(client, "rate", time.Minute, time.Hour)
+	if err != nil {
+		t.Fatalf("NewWindowedCounter returned error: %v", err)
+	}
+	counter.now = func() time.Time { return time.Unix(600, 0) }
+
+	client.EXPECT().
+		DoMulti(ctx,
+			rueidismock.Match("GET", "rate:api:600"),
+			rueidismock.Match("GET", "rate:api:540"),
+			rueidismock.Match("GET", "rate:api:480"),
+		).
+		Return([]rueidis.RedisResult{
+			rueidismock.Result(rueidismock.RedisBlobString("3")),
+			rueidismock.Result(rueidismock.RedisNil()),
+			rueidismock.Result(rueidismock.RedisBlobString("4")),
+		})
+
+	sum, err := counter.Sum(ctx, "api", 3*time.Minute)
+	if err != nil {
+		t.Fatalf("Sum returned error: %v", err)
+	}
+	if sum != 7 {
+		t.Fatalf("expected 7, got %d", sum)
+	}
+}
+
+func TestWindowedCounterRejectsWindowBeyondRetention(t *testing.T) {
+	t.Parallel()
+
+	counter, err := NewWindowedCounter(nil, "rate", time.Minute, time.Hour)
+	if err != nil {
+		t.Fatalf("NewWindowedCounter returned error: %v", err)
+	}
+
+	if _, err := counter.Sum(context.Background(), "api", 2*time.Hour); err == nil {
+		t.Fatalf("expected error for window beyond retention")
+	}
+}
+
+func TestNewWindowedCounterRejectsInvalidBuckets(t *testing.T) {
+	t.Parallel()
+
+	for _, bucket := range []time.Duration{0, -time.Minute, 500 * time.Millisecond, 1500 * time.Millisecond} {
+		if _, err := NewWindowedCounter(nil, "rate", bucket, time.Hour); err == nil {
+			t.Errorf("expected error for bucket %s", bucket)
+		}
+	}
+	if _, err := NewWindowedCounter(nil, "rate", time.Minute, -time.Hour); err == nil {
+		t.Error("expected error for negative retention")
+	}
+}
+
+func TestCounterAppliesSubSecondTTL(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	client := rueidismock.NewClient(ctrl)
+	counter := NewCounter(client, "burst", WithDefaultExpiration(500*time.Millisecond))
+
+	client.EXPECT().
+		DoMulti(ctx,
+			rueidismock.Match("INCRBY", "burst:user", "1"),
+			rueidismock.Match("PEXPIRE", "burst:user", "500", "NX"),
+		).
+		Return([]rueidis.RedisResult{
+			rueidismock.Result(rueidismock.RedisInt64(1)),
+			rueidismock.Result(rueidismock.RedisInt64(1)),
+		})
+
+	if _, err := counter.Incr(ctx, "user"); err != nil {
+		t.Fatalf("Incr returned error: %v", err)
+	}
+}
+
+func TestCountersHonorHashTag(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	client := rueidismock.NewClient(ctrl)
+	counter := NewCounter(client, "hits", WithHashTag())
+	windowed, err := NewWindowedCounter(client, "rate", time.Minute, time.Hour, WithHashTag())
+	if err != nil {
+		t.Fatalf("NewWindowedCounter returned error: %v", err)
+	}
+	windowed.now = func() time.Time { return time.Unix(600, 0) }
+
+	client.EXPECT().
+		Do(ctx, rueidismock.Match("INCRBY", "{hits}:page", "1")).
+		Return(rueidismock.Result(rueidismock.RedisInt64(1)))
+	client.EXPECT().
+		DoMulti(ctx,
+			rueidismock.Match("GET", "{rate}:api:600"),
+			rueidismock.Match("GET", "{rate}:api:540"),
+		).
+		Return([]rueidis.RedisResult{
+			rueidismock.Result(rueidismock.RedisBlobString("3")),
+			rueidismock.Result(rueidismock.RedisNil()),
+		})
+
+	if _, err := counter.Incr(ctx, "page"); err != nil {
+		t.Fatalf("Incr returned error: %v", err)
+	}
+	if sum, err := windowed.Sum(ctx, "api", 2*time.Minute); err != nil || sum != 3 {
+		t.Fatalf("expected 3, got %d, %v", sum, err)
+	}
+}
@@ -0,0 +1,200 @@
+package rv
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/redis/rueidis"
+)
+
+// dumpMagic and dumpVersion open every dump written by Export.
+const (
+	dumpMagic   = "RVDUMP"
+	dumpVersion = 1
+)
+
+// Dump record markers. A dump ends with dumpEnd, so truncated files are detected.
+const (
+	dumpEnd    byte = 0
+	dumpRecord byte = 1
+)
+
+// maxDumpField bounds the length of a key or value read by Import. Fields are read as their bytes arrive, so a
+// corrupted length in a truncated dump does not allocate the declared size.
+const maxDumpField = 512 << 20
+
+// ErrInvalidDump is returned by Import when the input is not a dump written by Export.
+var ErrInvalidDump = errors.New("invalid rv dump")
+
+// Export streams every key of the namespace matching the pattern (without the namespace prefix) to w, together
+// with its stored encoding and remaining TTL, and returns how many keys were written. Passing an empty pattern
+// matches all keys in the namespace. Chunked values are exported whole.
+//
+// The dump starts with the magic "RVDUMP" and a version byte, followed by records of a 1 byte marker, the
+// key relative to the namespace and the value, each prefixed with its uvarint length, and the remaining TTL in
+// milliseconds as a varint, 0 meaning no expiration. A 0 marker ends the dump.
+func (r *Value[T]) Export(ctx context.Context, w io.Writer, pattern string) (_ int64, err error) {
+	ctx, op := r.telemetry.start(ctx, "export")
+	defer func() { op.end(err) }()
+
+	out := bufio.NewWriter(w)
+	if _, err := out.Write(append([]byte(dumpMagic), dumpVersion)); err != nil {
+		return 0, fmt.Errorf("failed to write dump: %w", err)
+	}
+
+	var exported int64
+	err = r.scanKeys(ctx, pattern, 0, func(keys []string) error {
+		batch, err := r.store.MGet(ctx, keys)
+		if err != nil {
+			return fmt.Errorf("failed to fetch export batch for %q: %w", pattern, err)
+		}
+		ttls, err := r.store.TTLs(ctx, keys)
+		if err != nil {
+			return fmt.Errorf("failed to read TTLs of export batch for %q: %w", pattern, err)
+		}
+
+		for _, rawKey := range keys {
+			data, ok := batch[rawKey]
+			if !ok || isInternal(data) {
+				continue
+			}
+
+			if data, err = r.resolveChunks(ctx, rawKey, data); err != nil {
+				if errors.Is(err, rueidis.Nil) {
+					continue
+				}
+				return fmt.Errorf("failed to read chunks of key %q: %w", rawKey, err)
+			}
+
+			ttl, ok := ttls[rawKey]
+			if !ok {
+				continue // expired since MGET
+			}
+
+			record := appendDumpRecord(nil, strings.TrimPrefix(rawKey, r.namespacedKey("")), data, ttl)
+			if _, err := out.Write(record); err != nil {
+				return fmt.Errorf("failed to write dump: %w", err)
+			}
+			exported++
+		}
+
+		return nil
+	})
+	if err != nil {
+		return exported, err
+	}
+
+	if err := out.WriteByte(dumpEnd); err != nil {
+		return exported, fmt.Errorf("failed to write dump: %w", err)
+	}
+	if err := out.Flush(); err != nil {
+		return exported, fmt.Errorf("failed to write dump: %w", err)
+	}
+
+	return exported, nil
+}
+
+// Import restores a dump written by Export into the namespace, which may differ from the exported one, and
+// returns how many keys were written. Existing keys are overwritten, and every key expires after the TTL it had
+// left when it was exported. Values are written as stored, without decoding them, but are checked against
+// WithMaxPayloadSize and split according to WithChunking.
+func (r *Value[T]) Import(ctx context.Context, rd io.Reader) (_ int64, err error) {
+	ctx, op := r.telemetry.start(ctx, "import")
+	defer func() { op.end(err) }()
+
+	in := bufio.NewReader(rd)
+
+	header := make([]byte, len(dumpMagic)+1)
+	if _, err := io.ReadFull(in, header); err != nil || !bytes.Equal(header[:len(dumpMagic)], []byte(dumpMagic)) {
+		return 0, fmt.Errorf("%w: missing header", ErrInvalidDump)
+	}
+	if header[len(dumpMagic)] != dumpVersion {
+		return 0, fmt.Errorf("%w: unsupported version %d", ErrInvalidDump, header[len(dumpMagic)])
+	}
+
+	var imported int64
+	for {
+		key, data, ttl, err := readDumpRecord(in)
+		if errors.Is(err, io.EOF) {
+			return imported, nil
+		}
+		if err != nil {
+			return imported, err
+		}
+
+		if err := r.checkPayload(key, data); err != nil {
+			return imported, err
+		}
+
+		args := SetArgs{TTL: ttl}
+		if r.config.chunkSize > 0 {
+			err = r.setChunked(ctx, r.namespacedKey(key), data, args)
+		} else {
+			err = r.store.Set(ctx, r.namespacedKey(key), data, args)
+		}
+		if err != nil {
+			return imported, fmt.Errorf("failed to import key %q: %w", key, err)
+		}
+		imported++
+	}
+}
+
+func appendDumpRecord(buf []byte, key string, value []byte, ttl time.Duration) []byte {
+	buf = append(buf, dumpRecord)
+	buf = binary.AppendUvarint(buf, uint64(len(key)))
+	buf = append(buf, key...)
+	buf = binary.AppendUvarint(buf, uint64(len(value)))
+	buf = append(buf, value...)
+	return binary.AppendVarint(buf, max(ttl.Milliseconds(), 0))
+}
+
+// readDumpRecord reads the next record, returning io.EOF at the end marker.
+func readDumpRecord(r *bufio.Reader) (string, []byte, time.Duration, error) {
+	marker, err := r.ReadByte()
+	if err != nil {
+		return "", nil, 0, fmt.Errorf("%w: truncated", ErrInvalidDump)
+	}
+	switch marker {
+	case dumpEnd:
+		return "", nil, 0, io.EOF
+	case dumpRecord:
+	default:
+		return "", nil, 0, fmt.Errorf("%w: unknown record marker %d", ErrInvalidDump, marker)
+	}
+
+	key, err := readDumpField(r)
+	if err != nil {
+		return "", nil, 0, err
+	}
+	value, err := readDumpField(r)
+	if err != nil {
+		return "", nil, 0, err
+	}
+	ms, err := binary.ReadVarint(r)
+	if err != nil || ms < 0 {
+		return "", nil, 0, fmt.Errorf("%w: truncated", ErrInvalidDump)
+	}
+
+	return string(key), value, time.Duration(ms) * time.Millisecond, nil
+}
+
+func readDumpField(r *bufio.Reader) ([]byte, error) {
+	n, err := binary.ReadUvarint(r)
+	if err != nil || n > maxDumpField {
+		return nil, fmt.Errorf("%w: invalid field length", ErrInvalidDump)
+	}
+
+	var field bytes.Buffer
+	if _, err := io.CopyN(&field, r, int64(n)); err != nil {
+		return nil, fmt.Errorf("%w: truncated", ErrInvalidDump)
+	}
+
+	return field.Bytes(), nil
+}
@@ -0,0 +1,90 @@
+package rv
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValueExportImportRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	source := NewMemoryStore()
+	value := NewValue[testPayload](nil, nil, "users", WithStore(source), WithChunking(16))
+
+	if err := value.Set(ctx, "1", &testPayload{Message: "alice"}, SetTTL(time.Hour)); err != nil {
+		t.Fatalf("Set returned error: %v", err)
+	}
+	if err := value.Set(ctx, "2", &testPayload{Message: strings.Repeat("b", 40)}); err != nil {
+		t.Fatalf("Set returned error: %v", err)
+	}
+
+	var dump bytes.Buffer
+	exported, err := value.Export(ctx, &dump, "")
+	if err != nil {
+		t.Fatalf("Export returned error: %v", err)
+	}
+	if exported != 2 {
+		t.Fatalf("expected 2 exported keys, got %d", exported)
+	}
+	if !bytes.HasPrefix(dump.Bytes(), []byte("RVDUMP\x01")) {
+		t.Fatalf("expected the dump header, got %q", dump.Bytes()[:7])
+	}
+
+	target := NewMemoryStore()
+	restored := NewValue[testPayload](nil, nil, "copy", WithStore(target))
+
+	imported, err := restored.Import(ctx, bytes.NewReader(dump.Bytes()))
+	if err != nil {
+		t.Fatalf("Import returned error: %v", err)
+	}
+	if imported != 2 {
+		t.Fatalf("expected 2 imported keys, got %d", imported)
+	}
+
+	got, err := restored.Get(ctx, "2")
+	if err != nil || got.Message != strings.Repeat("b", 40) {
+		t.Fatalf("expected the chunked value to be restored whole, got %v, %v", got, err)
+	}
+	if keys := target.Keys(); len(keys) != 2 {
+		t.Fatalf("expected unchunked keys without WithChunking, got %v", keys)
+	}
+	if ttl, _ := target.TTL(ctx, "copy:1"); ttl != time.Hour {
+		t.Fatalf("expected the TTL to be restored, got %v", ttl)
+	}
+	if ttl, _ := target.TTL(ctx, "copy:2"); ttl != -1 {
+		t.Fatalf("expected no expiration to be restored, got %v", ttl)
+	}
+}
+
+func TestValueImportRejectsInvalidDumps(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	value := NewValue[testPayload](nil, nil, "users", WithStore(NewMemoryStore()))
+
+	var dump bytes.Buffer
+	if _, err := NewValue[testPayload](nil, nil, "users", WithStore(NewMemoryStore())).Export(ctx, &dump, ""); err != nil {
+		t.Fatalf("Export returned error: %v", err)
+	}
+
+	inputs := map[string][]byte{
+		"header":    []byte("REDIS0011"),
+		"version":   []byte("RVDUMP\x02\x00"),
+		"truncated": append([]byte("RVDUMP\x01\x01\x05ab"), 0),
+		"no end":    dump.Bytes()[:dump.Len()-1],
+		// A corrupted length must fail on the missing bytes instead of allocating the declared size.
+		"length": binary.AppendUvarint([]byte("RVDUMP\x01\x01"), maxDumpField),
+	}
+
+	for name, input := range inputs {
+		if _, err := value.Import(ctx, bytes.NewReader(input)); !errors.Is(err, ErrInvalidDump) {
+			t.Errorf("%s: expected ErrInvalidDump, got %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,187 @@
+package rv
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/rueidis"
+)
+
+// ErrNoLeader is returned by Election.Leader when nobody currently holds the leadership.
+var ErrNoLeader = errors.New("no leader elected")
+
+// electionRenewScript extends the leadership key only while it is still owned by the caller.
+var electionRenewScript = rueidis.NewLuaScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+return 0
+`)
+
+// electionResignScript removes the leadership key only while it is still owned by the caller.
+var electionResignScript = rueidis.NewLuaScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+// Election campaigns for a named leadership role using a Redis key with a TTL that the leader keeps renewing.
+type Election struct {
+	client rueidis.Client
+	key    string
+	id     string
+	ttl    time.Duration
+
+	leading atomic.Bool
+}
+
+// NewElection instantiates an Election for the role stored under key.
+// The id identifies this candidate and is reported by Leader while it holds the role.
+// The leadership expires after ttl unless renewed; renewals happen every third of ttl.
+func NewElection(client rueidis.Client, key string, id string, ttl time.Duration) *Election {
+	return &Election{client: client, key: key, id: id, ttl: ttl}
+}
+
+// Elect campaigns for the role stored under key with a hostname and PID based identity and a 15 second TTL,
+// invoking onLeader every time leadership is won. It blocks until ctx is done.
+func Elect(ctx context.Context, client rueidis.Client, key string, onLeader func(ctx context.Context)) error {
+	return NewElection(client, key, defaultIdentity(), 15*time.Second).Run(ctx, onLeader)
+}
+
+// ID returns the identity this candidate campaigns with.
+func (e *Election) ID() string {
+	return e.id
+}
+
+// IsLeader reports whether this candidate currently holds the leadership.
+func (e *Election) IsLeader() bool {
+	return e.leading.Load()
+}
+
+// Leader returns the identity of the current leader, or ErrNoLeader when the role is vacant.
+func (e *Election) Leader(ctx context.Context) (string, error) {
+	id, err := e.client.Do(ctx, e.client.B().Get().Key(e.key).Build()).ToString()
+	if err != nil {
+		if rueidis.IsRedisNil(err) {
+			return "", ErrNoLeader
+		}
+		return "", fmt.Errorf("failed to get leader: %w", err)
+	}
+
+	return id, nil
+}
+
+// Run campaigns for leadership until ctx is done. Whenever leadership is won, onLeader is called with a context
+// that is canceled as soon as the leadership is lost or ctx is done. When onLeader returns, the leadership is
+// resigned and the candidate campaigns again. Run waits for onLeader to return before campaigning again, so
+// onLeader must honor its context.
+func (e *Election) Run(ctx context.Context, onLeader func(ctx context.Context)) error {
+	ticker := time.NewTicker(e.ttl / 3)
+	defer ticker.Stop()
+
+	for {
+		// Campaign failures are retried on the next tick; Redis being briefly unavailable must not stop the loop.
+		if acquired, err := e.campaign(ctx); err == nil && acquired {
+			e.lead(ctx, onLeader)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func (e *Election) campaign(ctx context.Context) (bool, error) {
+	err := e.client.Do(ctx, e.client.B().Set().Key(e.key).Value(e.id).Nx().Px(e.ttl).Build()).Error()
+	if err != nil {
+		if rueidis.IsRedisNil(err) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return true, nil
+}
+
+// lead runs onLeader while renewing the leadership, and returns once the leadership is lost or given up.
+// IsLeader reports false as soon as the leadership is lost, without waiting for onLeader to return.
+func (e *Election) lead(ctx context.Context, onLeader func(ctx context.Context)) {
+	e.leading.Store(true)
+	defer e.leading.Store(false)
+
+	leaderCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		onLeader(leaderCtx)
+	}()
+
+	ticker := time.NewTicker(e.ttl / 3)
+	defer ticker.Stop()
+
+	expiry := time.NewTimer(e.ttl)
+	defer expiry.Stop()
+
+	for {
+		select {
+		case <-done:
+			e.resign(context.WithoutCancel(ctx))
+			return
+		case <-ctx.Done():
+			e.leading.Store(false)
+			cancel()
+			<-done
+			e.resign(context.WithoutCancel(ctx))
+			return
+		case <-expiry.C:
+			// Renewals kept failing until the key expired; another candidate may already lead.
+			e.leading.Store(false)
+			cancel()
+			<-done
+			return
+		case <-ticker.C:
+			started := time.Now()
+
+			renewed, err := electionRenewScript.Exec(ctx, e.client, []string{e.key}, []string{
+				e.id,
+				strconv.FormatInt(e.ttl.Milliseconds(), 10),
+			}).AsInt64()
+			if err != nil {
+				continue // retried on the next tick until the expiry timer fires
+			}
+			if renewed == 0 {
+				e.leading.Store(false)
+				cancel()
+				<-done
+				return
+			}
+
+			expiry.Reset(e.ttl - time.Since(started))
+		}
+	}
+}
+
+func (e *Election) resign(ctx context.Context) {
+	// A failed resignation only delays the next election until the key expires.
+	_ = electionResignScript.Exec(ctx, e.client, []string{e.key}, []string{e.id}).Error()
+}
+
+// defaultIdentity builds an identity from the hostname and process ID.
+func defaultIdentity() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+
+	return hostname + "-" + strconv.Itoa(os.Getpid())
+}
@@ -0,0 +1,122 @@
+package rv
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	rueidismock "github.com/redis/rueidis/mock"
+	"go.uber.org/mock/gomock"
+)
+
+func TestElectionLeaderReturnsIdentity(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	client := rueidismock.NewClient(ctrl)
+	election := NewElection(client, "scheduler", "pod-a", time.Second)
+
+	gomock.InOrder(
+		client.EXPECT().
+			Do(ctx, rueidismock.Match("GET", "scheduler")).
+			Return(rueidismock.Result(rueidismock.RedisBlobString("pod-b"))),
+		client.EXPECT().
+			Do(ctx, rueidismock.Match("GET", "scheduler")).
+			Return(rueidismock.Result(rueidismock.RedisNil())),
+	)
+
+	leader, err := election.Leader(ctx)
+	if err != nil {
+		t.Fatalf("Leader returned error: %v", err)
+	}
+	if leader != "pod-b" {
+		t.Fatalf("expected pod-b, got %q", leader)
+	}
+
+	if _, err := election.Leader(ctx); !errors.Is(err, ErrNoLeader) {
+		t.Fatalf("expected ErrNoLeader, got %v", err)
+	}
+}
+
+func TestElectionRunInvokesLeaderAndResigns(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	client := rueidismock.NewClient(ctrl)
+	election := NewElection(client, "cron", "pod-a", time.Minute)
+
+	gomock.InOrder(
+		client.EXPECT().
+			Do(gomock.Any(), rueidismock.Match("SET", "cron", "pod-a", "NX", "PX", "60000")).
+			Return(rueidismock.Result(rueidismock.RedisString("OK"))),
+		client.EXPECT().
+			Do(gomock.Any(), matchEvalshaCommand("cron")).
+			Return(rueidismock.Result(rueidismock.RedisInt64(1))),
+	)
+
+	var led bool
+	err := election.Run(ctx, func(leaderCtx context.Context) {
+		led = election.IsLeader()
+		cancel()
+		<-leaderCtx.Done()
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if !led {
+		t.Fatalf("expected IsLeader to report leadership inside onLeader")
+	}
+	if election.IsLeader() {
+		t.Fatalf("expected leadership to be released after Run returned")
+	}
+}
+
+func TestElectionIsLeaderClearedWhenRenewalFails(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	client := rueidismock.NewClient(ctrl)
+	election := NewElection(client, "cron", "pod-a", 30*time.Millisecond)
+
+	client.EXPECT().
+		Do(gomock.Any(), rueidismock.Match("SET", "cron", "pod-a", "NX", "PX", "30")).
+		Return(rueidismock.Result(rueidismock.RedisString("OK")))
+	client.EXPECT().
+		Do(gomock.Any(), rueidismock.Match("SET", "cron", "pod-a", "NX", "PX", "30")).
+		Return(rueidismock.Result(rueidismock.RedisNil())).
+		AnyTimes()
+	client.EXPECT().
+		Do(gomock.Any(), matchEvalshaCommand("cron")).
+		Return(rueidismock.Result(rueidismock.RedisInt64(0)))
+
+	var cleared bool
+	err := election.Run(ctx, func(context.Context) {
+		// Ignores its context on purpose: IsLeader must not wait for onLeader to return.
+		deadline := time.Now().Add(2 * time.Second)
+		for election.IsLeader() && time.Now().Before(deadline) {
+			time.Sleep(time.Millisecond)
+		}
+		cleared = !election.IsLeader()
+		cancel()
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if !cleared {
+		t.Fatal("expected IsLeader to report false once renewal failed")
+	}
+}
@@ -0,0 +1,227 @@
+package rv
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/redis/rueidis"
+)
+
+// ErrNoCompanionValue is returned by Geo.Put when the index was created without a companion Value.
+var ErrNoCompanionValue = errors.New("geo index has no companion value")
+
+// GeoLocation is a member of a Geo index and its coordinates.
+type GeoLocation struct {
+	Member    string
+	Longitude float64
+	Latitude  float64
+}
+
+// GeoResult is a member found by a Geo search, with its distance from the search center in meters and, when the
+// index has a companion Value, its payload. Value is nil when the member has no payload stored.
+type GeoResult[T any] struct {
+	GeoLocation
+	Distance float64
+	Value    *T
+}
+
+type geoSearch struct {
+	count      int64
+	descending bool
+}
+
+// GeoOption configures a Geo search.
+type GeoOption func(*geoSearch)
+
+// GeoCount limits a search to the n members nearest to the center, or farthest with GeoDescending.
+func GeoCount(n int64) GeoOption {
+	return func(s *geoSearch) {
+		s.count = n
+	}
+}
+
+// GeoDescending sorts search results from the farthest to the nearest member.
+func GeoDescending() GeoOption {
+	return func(s *geoSearch) {
+		s.descending = true
+	}
+}
+
+// Geo is a geospatial index of named members over a Redis GEO set. The members' payloads can be kept in a
+// companion Value under the same names, in which case searches return them along with the locations.
+type Geo[T any] struct {
+	client rueidis.Client
+	key    string
+	values *Value[T]
+}
+
+// NewGeo instantiates a Geo helper for the provided key. values may be nil when members carry no payload.
+func NewGeo[T any](client rueidis.Client, key string, values *Value[T]) *Geo[T] {
+	return &Geo[T]{client: client, key: key, values: values}
+}
+
+// Add inserts or moves the members and returns how many of them were new.
+func (g *Geo[T]) Add(ctx context.Context, locations ...GeoLocation) (int64, error) {
+	if len(locations) == 0 {
+		return 0, nil
+	}
+
+	cmd := g.client.B().Geoadd().Key(g.key).LongitudeLatitudeMember()
+	for _, l := range locations {
+		cmd = cmd.LongitudeLatitudeMember(l.Longitude, l.Latitude, l.Member)
+	}
+
+	added, err := g.client.Do(ctx, cmd.Build()).AsInt64()
+	if err != nil {
+		return 0, fmt.Errorf("failed to add geo members: %w", err)
+	}
+
+	return added, nil
+}
+
+// Put stores the member's payload in the companion Value and then inserts or moves the member, so searches never
+// find a member before its payload.
+func (g *Geo[T]) Put(ctx context.Context, location GeoLocation, value *T, setOptions ...SetOption) error {
+	if g.values == nil {
+		return ErrNoCompanionValue
+	}
+
+	if err := g.values.Set(ctx, location.Member, value, setOptions...); err != nil {
+		return err
+	}
+
+	_, err := g.Add(ctx, location)
+	return err
+}
+
+// Remove deletes the members from the index and, when the index has a companion Value, their payloads.
+func (g *Geo[T]) Remove(ctx context.Context, members ...string) error {
+	if len(members) == 0 {
+		return nil
+	}
+
+	if err := g.client.Do(ctx, g.client.B().Zrem().Key(g.key).Member(members...).Build()).Error(); err != nil {
+		return fmt.Errorf("failed to remove geo members: %w", err)
+	}
+
+	if g.values != nil {
+		for _, member := range members {
+			if err := g.values.Delete(ctx, member); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Position returns the coordinates of a member, or an error wrapping rueidis.Nil if it is not in the index.
+func (g *Geo[T]) Position(ctx context.Context, member string) (GeoLocation, error) {
+	resp, err := g.client.Do(ctx, g.client.B().Geopos().Key(g.key).Member(member).Build()).ToArray()
+	if err != nil {
+		return GeoLocation{}, fmt.Errorf("failed to get geo position: %w", err)
+	}
+	if len(resp) != 1 {
+		return GeoLocation{}, errors.New("failed to get geo position: unexpected reply length")
+	}
+
+	coordinates, err := resp[0].ToArray()
+	if err != nil {
+		return GeoLocation{}, fmt.Errorf("failed to get geo position: %w", err)
+	}
+	if len(coordinates) != 2 {
+		return GeoLocation{}, errors.New("failed to get geo position: unexpected reply length")
+	}
+
+	location := GeoLocation{Member: member}
+	if location.Longitude, err = coordinates[0].AsFloat64(); err != nil {
+		return GeoLocation{}, fmt.Errorf("failed to get geo position: %w", err)
+	}
+	if location.Latitude, err = coordinates[1].AsFloat64(); err != nil {
+		return GeoLocation{}, fmt.Errorf("failed to get geo position: %w", err)
+	}
+
+	return location, nil
+}
+
+// SearchRadius returns the members within radius meters of the center, nearest first.
+func (g *Geo[T]) SearchRadius(ctx context.Context, longitude, latitude, radius float64, options ...GeoOption) ([]GeoResult[T], error) {
+	return g.search(ctx, options, func(s geoSearch) rueidis.Completed {
+		cmd := g.client.B().Geosearch().Key(g.key).Fromlonlat(longitude, latitude).Byradius(radius).M()
+		if s.descending {
+			cmd.Desc()
+		} else {
+			cmd.Asc()
+		}
+		if s.count > 0 {
+			cmd.Count(s.count)
+		}
+
+		return cmd.Withcoord().Withdist().Build()
+	})
+}
+
+// SearchBox returns the members within the axis-aligned box of width and height meters centered on the center,
+// nearest first.
+func (g *Geo[T]) SearchBox(ctx context.Context, longitude, latitude, width, height float64, options ...GeoOption) ([]GeoResult[T], error) {
+	return g.search(ctx, options, func(s geoSearch) rueidis.Completed {
+		cmd := g.client.B().Geosearch().Key(g.key).Fromlonlat(longitude, latitude).Bybox(width).Height(height).M()
+		if s.descending {
+			cmd.Desc()
+		} else {
+			cmd.Asc()
+		}
+		if s.count > 0 {
+			cmd.Count(s.count)
+		}
+
+		return cmd.Withcoord().Withdist().Build()
+	})
+}
+
+// search runs the GEOSEARCH command built for the options and joins the payloads of the results.
+func (g *Geo[T]) search(ctx context.Context, options []GeoOption, build func(s geoSearch) rueidis.Completed) ([]GeoResult[T], error) {
+	var s geoSearch
+	for _, opt := range options {
+		opt(&s)
+	}
+
+	found, err := g.client.Do(ctx, build(s)).AsGeosearch()
+	if err != nil {
+		return nil, fmt.Errorf("failed to search geo index: %w", err)
+	}
+
+	results := make([]GeoResult[T], len(found))
+	for i, f := range found {
+		results[i] = GeoResult[T]{
+			GeoLocation: GeoLocation{Member: f.Name, Longitude: f.Longitude, Latitude: f.Latitude},
+			Distance:    f.Dist,
+		}
+	}
+
+	if g.values != nil && len(results) > 0 {
+		if err := g.join(ctx, results); err != nil {
+			return nil, err
+		}
+	}
+
+	return results, nil
+}
+
+// join loads the payloads of the results from the companion Value in a single MGET.
+func (g *Geo[T]) join(ctx context.Context, results []GeoResult[T]) error {
+	keys := make([]string, len(results))
+	for i, r := range results {
+		keys[i] = g.values.namespacedKey(r.Member)
+	}
+
+	batch, err := g.values.store.MGet(ctx, keys)
+	if err != nil {
+		return fmt.Errorf("failed to fetch geo payloads: %w", err)
+	}
+
+	return g.values.decodeBatch(ctx, keys, batch, func(i int, value *T, _ int) {
+		results[i].Value = value
+	})
+}
@@ -0,0 +1,124 @@
+package rv
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/redis/rueidis"
+	rueidismock "github.com/redis/rueidis/mock"
+	"go.uber.org/mock/gomock"
+)
+
+func geoReply(name, dist, longitude, latitude string) rueidis.RedisMessage {
+	return rueidismock.RedisArray(
+		rueidismock.RedisBlobString(name),
+		rueidismock.RedisBlobString(dist),
+		rueidismock.RedisArray(rueidismock.RedisBlobString(longitude), rueidismock.RedisBlobString(latitude)),
+	)
+}
+
+func TestGeoPutAndSearchRadiusJoinsPayloads(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	client := rueidismock.NewClient(ctrl)
+	store := NewMemoryStore()
+	couriers := NewGeo(client, "couriers:seoul", NewValue[testPayload](nil, nil, "courier", WithStore(store)))
+
+	client.EXPECT().
+		Do(ctx, rueidismock.Match("GEOADD", "couriers:seoul", "126.978", "37.5665", "alice")).
+		Return(rueidismock.Result(rueidismock.RedisInt64(1)))
+
+	if err := couriers.Put(ctx, GeoLocation{Member: "alice", Longitude: 126.978, Latitude: 37.5665}, &testPayload{Message: "on bike"}); err != nil {
+		t.Fatalf("Put returned error: %v", err)
+	}
+
+	client.EXPECT().
+		Do(ctx, rueidismock.Match("GEOSEARCH", "couriers:seoul", "FROMLONLAT", "126.97", "37.56", "BYRADIUS", "1500", "m", "ASC", "COUNT", "2", "WITHCOORD", "WITHDIST")).
+		Return(rueidismock.Result(rueidismock.RedisArray(
+			geoReply("alice", "1050.5", "126.978", "37.5665"),
+			geoReply("bob", "1320", "126.985", "37.57"),
+		)))
+
+	results, err := couriers.SearchRadius(ctx, 126.97, 37.56, 1500, GeoCount(2))
+	if err != nil {
+		t.Fatalf("SearchRadius returned error: %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("expected 2 results, got %d", len(results))
+	}
+
+	alice := results[0]
+	if alice.Member != "alice" || alice.Distance != 1050.5 || alice.Longitude != 126.978 || alice.Latitude != 37.5665 {
+		t.Fatalf("unexpected first result: %+v", alice)
+	}
+	if alice.Value == nil || alice.Value.Message != "on bike" {
+		t.Fatalf("expected the payload to be joined, got %v", alice.Value)
+	}
+	if results[1].Value != nil {
+		t.Fatalf("expected no payload for a member without one, got %v", results[1].Value)
+	}
+}
+
+func TestGeoSearchBoxWithoutCompanion(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	client := rueidismock.NewClient(ctrl)
+	stations := NewGeo[testPayload](client, "stations", nil)
+
+	client.EXPECT().
+		Do(ctx, rueidismock.Match("GEOSEARCH", "stations", "FROMLONLAT", "2.35", "48.85", "BYBOX", "2000", "1000", "m", "DESC", "WITHCOORD", "WITHDIST")).
+		Return(rueidismock.Result(rueidismock.RedisArray(geoReply("chatelet", "420", "2.347", "48.858"))))
+
+	results, err := stations.SearchBox(ctx, 2.35, 48.85, 2000, 1000, GeoDescending())
+	if err != nil {
+		t.Fatalf("SearchBox returned error: %v", err)
+	}
+	if len(results) != 1 || results[0].Member != "chatelet" || results[0].Distance != 420 || results[0].Value != nil {
+		t.Fatalf("unexpected results: %+v", results)
+	}
+
+	if err := stations.Put(ctx, GeoLocation{Member: "chatelet"}, &testPayload{}); !errors.Is(err, ErrNoCompanionValue) {
+		t.Fatalf("expected ErrNoCompanionValue, got %v", err)
+	}
+}
+
+func TestGeoPosition(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	client := rueidismock.NewClient(ctrl)
+	couriers := NewGeo[testPayload](client, "couriers", nil)
+
+	client.EXPECT().
+		Do(ctx, rueidismock.Match("GEOPOS", "couriers", "alice")).
+		Return(rueidismock.Result(rueidismock.RedisArray(
+			rueidismock.RedisArray(rueidismock.RedisBlobString("126.978"), rueidismock.RedisBlobString("37.5665")),
+		)))
+	client.EXPECT().
+		Do(ctx, rueidismock.Match("GEOPOS", "couriers", "bob")).
+		Return(rueidismock.Result(rueidismock.RedisArray(rueidismock.RedisNil())))
+
+	location, err := couriers.Position(ctx, "alice")
+	if err != nil {
+		t.Fatalf("Position returned error: %v", err)
+	}
+	if location != (GeoLocation{Member: "alice", Longitude: 126.978, Latitude: 37.5665}) {
+		t.Fatalf("unexpected position: %+v", location)
+	}
+
+	if _, err := couriers.Position(ctx, "bob"); !errors.Is(err, rueidis.Nil) {
+		t.Fatalf("expected rueidis.Nil, got %v", err)
+	}
+}
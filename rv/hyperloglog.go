@@ -0,0 +1,112 @@
+package rv
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/redis/rueidis"
+)
+
+// HyperLogLog is a namespaced set of HyperLogLogs, estimating the number of distinct items added to each with a
+// standard error of 0.81% in at most 12 KiB. Items are encoded with CBOR, the same encoding Value uses.
+type HyperLogLog[K any] struct {
+	client rueidis.Client
+	key    string
+
+	config valueConfig
+}
+
+// NewHyperLogLog instantiates a HyperLogLog helper for the provided key prefix.
+// WithDefaultExpiration sets a TTL on the first write to a HyperLogLog, which requires Redis 7.0 or newer.
+// WithHashTag places every HyperLogLog of the namespace in one Redis Cluster slot, so Count and Merge can span
+// several names.
+func NewHyperLogLog[K any](client rueidis.Client, key string, options ...Option) *HyperLogLog[K] {
+	h := &HyperLogLog[K]{client: client, key: key}
+
+	for _, opt := range options {
+		opt(&h.config)
+	}
+
+	if h.config.hashTag {
+		h.key = "{" + key + "}"
+	}
+
+	return h
+}
+
+// Add inserts the items into the named HyperLogLog and reports whether its estimate changed.
+func (h *HyperLogLog[K]) Add(ctx context.Context, name string, items ...K) (bool, error) {
+	elements := make([]string, len(items))
+	for i, item := range items {
+		encoded, err := cbor.Marshal(item)
+		if err != nil {
+			return false, fmt.Errorf("failed to encode item: %w", err)
+		}
+		elements[i] = rueidis.BinaryString(encoded)
+	}
+
+	cmds := rueidis.Commands{h.client.B().Pfadd().Key(h.namespacedKey(name)).Element(elements...).Build()}
+	if h.config.expires != nil {
+		cmds = append(cmds, h.client.B().Pexpire().Key(h.namespacedKey(name)).Milliseconds(h.config.expires.Milliseconds()).Nx().Build())
+	}
+
+	resp := h.client.DoMulti(ctx, cmds...)
+	for _, r := range resp[1:] {
+		if err := r.Error(); err != nil {
+			return false, fmt.Errorf("failed to add to hyperloglog: %w", err)
+		}
+	}
+
+	changed, err := resp[0].AsInt64()
+	if err != nil {
+		return false, fmt.Errorf("failed to add to hyperloglog: %w", err)
+	}
+
+	return changed == 1, nil
+}
+
+// Count returns the estimated number of distinct items in the union of the named HyperLogLogs.
+// Missing HyperLogLogs count as empty. On Redis Cluster, all names must hash to the same slot, e.g. with
+// WithHashTag.
+func (h *HyperLogLog[K]) Count(ctx context.Context, names ...string) (int64, error) {
+	count, err := h.client.Do(ctx, h.client.B().Pfcount().Key(h.keys(names)...).Build()).AsInt64()
+	if err != nil {
+		return 0, fmt.Errorf("failed to count hyperloglog: %w", err)
+	}
+
+	return count, nil
+}
+
+// Merge stores the union of the source HyperLogLogs, and of dest itself, into dest.
+// On Redis Cluster, all names must hash to the same slot, e.g. with WithHashTag.
+func (h *HyperLogLog[K]) Merge(ctx context.Context, dest string, sources ...string) error {
+	cmd := h.client.B().Pfmerge().Destkey(h.namespacedKey(dest)).Sourcekey(h.keys(sources)...).Build()
+	if err := h.client.Do(ctx, cmd).Error(); err != nil {
+		return fmt.Errorf("failed to merge hyperloglog: %w", err)
+	}
+
+	return nil
+}
+
+// Delete removes the named HyperLogLog.
+func (h *HyperLogLog[K]) Delete(ctx context.Context, name string) error {
+	if err := h.client.Do(ctx, h.client.B().Del().Key(h.namespacedKey(name)).Build()).Error(); err != nil {
+		return fmt.Errorf("failed to delete hyperloglog: %w", err)
+	}
+
+	return nil
+}
+
+func (h *HyperLogLog[K]) keys(names []string) []string {
+	keys := make([]string, len(names))
+	for i, name := range names {
+		keys[i] = h.namespacedKey(name)
+	}
+
+	return keys
+}
+
+func (h *HyperLogLog[K]) namespacedKey(name string) string {
+	return h.key + ":" + name
+}
@@ -0,0 +1,95 @@
+package rv
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/redis/rueidis"
+	rueidismock "github.com/redis/rueidis/mock"
+	"go.uber.org/mock/gomock"
+)
+
+func TestHyperLogLogAddAppliesTTL(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	client := rueidismock.NewClient(ctrl)
+	hll := NewHyperLogLog[string](client, "visitors", WithDefaultExpiration(time.Hour))
+
+	element, err := cbor.Marshal("alice")
+	if err != nil {
+		t.Fatalf("failed to encode element: %v", err)
+	}
+
+	client.EXPECT().
+		DoMulti(ctx,
+			rueidismock.Match("PFADD", "visitors:2026-10-18", string(element)),
+			rueidismock.Match("PEXPIRE", "visitors:2026-10-18", "3600000", "NX"),
+		).
+		Return([]rueidis.RedisResult{
+			rueidismock.Result(rueidismock.RedisInt64(1)),
+			rueidismock.Result(rueidismock.RedisInt64(1)),
+		})
+
+	changed, err := hll.Add(ctx, "2026-10-18", "alice")
+	if err != nil {
+		t.Fatalf("Add returned error: %v", err)
+	}
+	if !changed {
+		t.Fatal("expected the estimate to change")
+	}
+}
+
+func TestHyperLogLogCountAndMerge(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	client := rueidismock.NewClient(ctrl)
+	hll := NewHyperLogLog[string](client, "visitors")
+
+	client.EXPECT().
+		Do(ctx, rueidismock.Match("PFCOUNT", "visitors:mon", "visitors:tue")).
+		Return(rueidismock.Result(rueidismock.RedisInt64(42)))
+	client.EXPECT().
+		Do(ctx, rueidismock.Match("PFMERGE", "visitors:week", "visitors:mon", "visitors:tue")).
+		Return(rueidismock.Result(rueidismock.RedisString("OK")))
+
+	count, err := hll.Count(ctx, "mon", "tue")
+	if err != nil {
+		t.Fatalf("Count returned error: %v", err)
+	}
+	if count != 42 {
+		t.Fatalf("expected 42, got %d", count)
+	}
+
+	if err := hll.Merge(ctx, "week", "mon", "tue"); err != nil {
+		t.Fatalf("Merge returned error: %v", err)
+	}
+}
+
+func TestHyperLogLogHonorsHashTag(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	client := rueidismock.NewClient(ctrl)
+	hll := NewHyperLogLog[string](client, "visitors", WithHashTag())
+
+	client.EXPECT().
+		Do(ctx, rueidismock.Match("PFMERGE", "{visitors}:week", "{visitors}:mon", "{visitors}:tue")).
+		Return(rueidismock.Result(rueidismock.RedisString("OK")))
+
+	if err := hll.Merge(ctx, "week", "mon", "tue"); err != nil {
+		t.Fatalf("Merge returned error: %v", err)
+	}
+}
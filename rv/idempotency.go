@@ -0,0 +1,250 @@
+package rv
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/redis/rueidis"
+)
+
+// IdempotencyHeader is the request header carrying the idempotency key.
+const IdempotencyHeader = "Idempotency-Key"
+
+var (
+	// ErrRequestInFlight is returned by Idempotency.Begin while another request with the same key is being processed.
+	ErrRequestInFlight = errors.New("request with the same idempotency key is in flight")
+	// ErrClaimLost is returned by Idempotency.Complete and Abort when the in-flight marker expired and the key was
+	// claimed again or completed by another caller, which is left untouched.
+	ErrClaimLost = errors.New("idempotency claim lost")
+)
+
+type idempotencyRecord[T any] struct {
+	Done   bool
+	Result *T
+	Owner  string `cbor:",omitempty"`
+}
+
+// Idempotency records the outcome of operations by idempotency key, so retried operations replay the
+// stored result instead of executing again.
+type Idempotency[T any] struct {
+	value       *Value[idempotencyRecord[T]]
+	inFlightTTL time.Duration
+	ttl         time.Duration
+}
+
+// NewIdempotency instantiates an Idempotency store for the provided key prefix.
+// In-flight markers expire after inFlightTTL so a crashed worker does not block retries forever,
+// and completed results are kept for ttl.
+func NewIdempotency[T any](client rueidis.Client, key string, inFlightTTL, ttl time.Duration, options ...Option) *Idempotency[T] {
+	return &Idempotency[T]{
+		value:       NewValue[idempotencyRecord[T]](client, nil, key, options...),
+		inFlightTTL: inFlightTTL,
+		ttl:         ttl,
+	}
+}
+
+// Begin claims the idempotency key. If the key is new, it returns a claim token and the caller must finish with
+// Complete or Abort, passing the token. If a result was already recorded, it is returned with an empty token.
+// If another caller holds the key, ErrRequestInFlight is returned.
+func (i *Idempotency[T]) Begin(ctx context.Context, key string) (*T, string, error) {
+	token := rand.Text()
+
+	// The marker may expire between SET NX and GET, so the claim is retried once.
+	for range 2 {
+		err := i.value.Set(ctx, key, &idempotencyRecord[T]{Owner: token}, SetNX(true), SetTTL(i.inFlightTTL))
+		if err == nil {
+			return nil, token, nil
+		}
+		if !errors.Is(err, rueidis.Nil) {
+			return nil, "", fmt.Errorf("failed to claim idempotency key: %w", err)
+		}
+
+		record, err := i.value.Get(ctx, key)
+		if err != nil {
+			if errors.Is(err, rueidis.Nil) {
+				continue
+			}
+			return nil, "", fmt.Errorf("failed to load idempotency record: %w", err)
+		}
+
+		if !record.Done {
+			return nil, "", ErrRequestInFlight
+		}
+
+		return record.Result, "", nil
+	}
+
+	return nil, "", ErrRequestInFlight
+}
+
+// Complete records the result for the idempotency key claimed with token, so later Begin calls replay it.
+// It returns ErrClaimLost if the claim expired and the key was claimed again meanwhile.
+func (i *Idempotency[T]) Complete(ctx context.Context, key, token string, result *T) error {
+	completed, err := i.value.encodeEntry(&idempotencyRecord[T]{Done: true, Result: result}, 0)
+	if err != nil {
+		return err
+	}
+	if err := i.value.checkPayload(key, completed); err != nil {
+		return err
+	}
+
+	if err := i.release(ctx, key, token, completed); err != nil {
+		return fmt.Errorf("failed to complete idempotency key: %w", err)
+	}
+
+	return nil
+}
+
+// Abort releases the idempotency key claimed with token without recording a result, allowing the operation to
+// be retried. It returns ErrClaimLost if the claim expired and the key was claimed again meanwhile.
+func (i *Idempotency[T]) Abort(ctx context.Context, key, token string) error {
+	if err := i.release(ctx, key, token, nil); err != nil {
+		return fmt.Errorf("failed to abort idempotency key: %w", err)
+	}
+
+	return nil
+}
+
+// release replaces the in-flight marker owned by token with the completed record, or deletes it when completed
+// is nil. Markers carry the token of their owner, so a caller whose marker expired cannot touch the record of
+// the next owner.
+func (i *Idempotency[T]) release(ctx context.Context, key, token string, completed []byte) error {
+	marker, err := i.value.encodeEntry(&idempotencyRecord[T]{Owner: token}, 0)
+	if err != nil {
+		return err
+	}
+
+	args, err := i.value.config.setArgs([]SetOption{SetTTL(i.ttl)})
+	if err != nil {
+		return err
+	}
+
+	released, err := i.value.store.CompareAndSwap(ctx, i.value.namespacedKey(key), marker, completed, args)
+	if err != nil {
+		return err
+	}
+	if !released {
+		return ErrClaimLost
+	}
+
+	return nil
+}
+
+// HTTPResponse is a recorded HTTP response replayed for retried requests.
+type HTTPResponse struct {
+	Status int
+	Header http.Header
+	Body   []byte
+}
+
+// IdempotencyMiddleware makes handlers idempotent for requests carrying the Idempotency-Key header.
+// Keys are scoped by the caller returned by scope, typically the authenticated principal, and by request method
+// and path, so clients reusing a key never receive each other's responses. Requests for which scope fails are
+// rejected with 401 Unauthorized. The first request runs the handler and its response is recorded; retries
+// receive the recorded response, and requests arriving while the first is still running receive 409 Conflict.
+// Responses with a 5xx status are not recorded, so those requests can be retried.
+func IdempotencyMiddleware(store *Idempotency[HTTPResponse], scope func(*http.Request) (string, error)) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			header := r.Header.Get(IdempotencyHeader)
+			if header == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			caller, err := scope(r)
+			if err != nil {
+				http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+				return
+			}
+
+			ctx := r.Context()
+			key := Key(caller, r.Method, r.URL.Path, header)
+
+			recorded, token, err := store.Begin(ctx, key)
+			if errors.Is(err, ErrRequestInFlight) {
+				http.Error(w, "a request with the same idempotency key is in progress", http.StatusConflict)
+				return
+			}
+			if err != nil {
+				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+				return
+			}
+
+			if token == "" {
+				for name, values := range recorded.Header {
+					w.Header()[name] = values
+				}
+				w.WriteHeader(recorded.Status)
+				_, _ = w.Write(recorded.Body)
+				return
+			}
+
+			recorder := &responseRecorder{ResponseWriter: w}
+
+			completed := false
+			defer func() {
+				// The handler panicked or failed server-side; release the key so the client may retry.
+				if !completed {
+					_ = store.Abort(context.WithoutCancel(ctx), key, token)
+				}
+			}()
+
+			next.ServeHTTP(recorder, r)
+
+			response := recorder.response()
+			if response.Status >= http.StatusInternalServerError {
+				return
+			}
+
+			// The response was already sent; if it cannot be recorded, the deferred Abort lets retries run again.
+			if err := store.Complete(context.WithoutCancel(ctx), key, token, response); err != nil {
+				return
+			}
+			completed = true
+		})
+	}
+}
+
+// responseRecorder writes through to the wrapped ResponseWriter while capturing the response.
+type responseRecorder struct {
+	http.ResponseWriter
+
+	status int
+	header http.Header
+	body   bytes.Buffer
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+		r.header = r.ResponseWriter.Header().Clone()
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(data []byte) (int, error) {
+	if r.status == 0 {
+		r.WriteHeader(http.StatusOK)
+	}
+	r.body.Write(data)
+	return r.ResponseWriter.Write(data)
+}
+
+func (r *responseRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+func (r *responseRecorder) response() *HTTPResponse {
+	if r.status == 0 {
+		r.status = http.StatusOK
+		r.header = r.ResponseWriter.Header().Clone()
+	}
+
+	return &HTTPResponse{Status: r.status, Header: r.header, Body: r.body.Bytes()}
+}
@@ -0,0 +1,247 @@
+package rv
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/fxamacker/cbor/v2"
+	rueidismock "github.com/redis/rueidis/mock"
+	"go.uber.org/mock/gomock"
+)
+
+func TestIdempotencyMiddlewareRecordsFirstResponse(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	client := rueidismock.NewClient(ctrl)
+	store := NewIdempotency[HTTPResponse](client, "idem", time.Minute, time.Hour)
+
+	gomock.InOrder(
+		client.EXPECT().
+			Do(gomock.Any(), matchSetCommand("idem:alice:POST:/charges:abc", func(tokens []string) bool {
+				return containsToken(tokens, "NX") && hasTokenSequence(tokens, "PX", "60000")
+			})).
+			Return(rueidismock.Result(rueidismock.RedisString("OK"))),
+		client.EXPECT().
+			Do(gomock.Any(), rueidismock.MatchFn(func(tokens []string) bool {
+				return matchEvalshaCommand("idem:alice:POST:/charges:abc").Matches(tokens) && tokens[len(tokens)-2] == "3600000"
+			}, "EVALSHA compare-and-swap PX 3600000")).
+			Return(rueidismock.Result(rueidismock.RedisInt64(1))),
+	)
+
+	calls := 0
+	handler := IdempotencyMiddleware(store, testScope)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte("charged"))
+	}))
+
+	request := httptest.NewRequest(http.MethodPost, "/charges", nil)
+	request.Header.Set(IdempotencyHeader, "abc")
+	request.Header.Set("X-User", "alice")
+	response := httptest.NewRecorder()
+	handler.ServeHTTP(response, request)
+
+	if calls != 1 {
+		t.Fatalf("expected handler to run once, ran %d times", calls)
+	}
+	if response.Code != http.StatusCreated || response.Body.String() != "charged" {
+		t.Fatalf("unexpected response %d %q", response.Code, response.Body.String())
+	}
+}
+
+func TestIdempotencyMiddlewareReplaysRecordedResponse(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	client := rueidismock.NewClient(ctrl)
+	store := NewIdempotency[HTTPResponse](client, "idem", time.Minute, time.Hour)
+
+	record := idempotencyRecord[HTTPResponse]{
+		Done: true,
+		Result: &HTTPResponse{
+			Status: http.StatusCreated,
+			Header: http.Header{"X-Charge": []string{"ch_1"}},
+			Body:   []byte("charged"),
+		},
+	}
+
+	gomock.InOrder(
+		client.EXPECT().
+			Do(gomock.Any(), matchSetCommand("idem:alice:POST:/charges:abc", func(tokens []string) bool {
+				return containsToken(tokens, "NX")
+			})).
+			Return(rueidismock.Result(rueidismock.RedisNil())),
+		client.EXPECT().
+			Do(gomock.Any(), matchGetCommand("idem:alice:POST:/charges:abc")).
+			Return(rueidismock.Result(rueidismock.RedisBlobString(string(mustMarshal(t, record))))),
+	)
+
+	handler := IdempotencyMiddleware(store, testScope)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Fatalf("handler must not run for a replayed request")
+	}))
+
+	request := httptest.NewRequest(http.MethodPost, "/charges", nil)
+	request.Header.Set(IdempotencyHeader, "abc")
+	request.Header.Set("X-User", "alice")
+	response := httptest.NewRecorder()
+	handler.ServeHTTP(response, request)
+
+	if response.Code != http.StatusCreated || response.Body.String() != "charged" {
+		t.Fatalf("unexpected response %d %q", response.Code, response.Body.String())
+	}
+	if response.Header().Get("X-Charge") != "ch_1" {
+		t.Fatalf("expected recorded header to be replayed")
+	}
+}
+
+func TestIdempotencyMiddlewareRejectsConcurrentDuplicate(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	client := rueidismock.NewClient(ctrl)
+	store := NewIdempotency[HTTPResponse](client, "idem", time.Minute, time.Hour)
+
+	gomock.InOrder(
+		client.EXPECT().
+			Do(gomock.Any(), matchSetCommand("idem:alice:POST:/charges:abc", func(tokens []string) bool {
+				return containsToken(tokens, "NX")
+			})).
+			Return(rueidismock.Result(rueidismock.RedisNil())),
+		client.EXPECT().
+			Do(gomock.Any(), matchGetCommand("idem:alice:POST:/charges:abc")).
+			Return(rueidismock.Result(rueidismock.RedisBlobString(string(mustMarshal(t, idempotencyRecord[HTTPResponse]{}))))),
+	)
+
+	handler := IdempotencyMiddleware(store, testScope)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Fatalf("handler must not run for a concurrent duplicate")
+	}))
+
+	request := httptest.NewRequest(http.MethodPost, "/charges", nil)
+	request.Header.Set(IdempotencyHeader, "abc")
+	request.Header.Set("X-User", "alice")
+	response := httptest.NewRecorder()
+	handler.ServeHTTP(response, request)
+
+	if response.Code != http.StatusConflict {
+		t.Fatalf("expected 409, got %d", response.Code)
+	}
+}
+
+func TestIdempotencyMiddlewareScopesKeysByCaller(t *testing.T) {
+	t.Parallel()
+
+	store := NewIdempotency[HTTPResponse](nil, "idem", time.Minute, time.Hour, WithStore(NewMemoryStore()))
+	handler := IdempotencyMiddleware(store, testScope)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("card of " + r.Header.Get("X-User")))
+	}))
+
+	for _, user := range []string{"alice", "bob", ""} {
+		request := httptest.NewRequest(http.MethodPost, "/charges", nil)
+		request.Header.Set(IdempotencyHeader, "abc")
+		request.Header.Set("X-User", user)
+		response := httptest.NewRecorder()
+		handler.ServeHTTP(response, request)
+
+		if user == "" {
+			if response.Code != http.StatusUnauthorized {
+				t.Fatalf("expected 401 without a caller, got %d", response.Code)
+			}
+			continue
+		}
+		if body := response.Body.String(); body != "card of "+user {
+			t.Fatalf("expected %s to receive their own response, got %q", user, body)
+		}
+	}
+}
+
+func TestIdempotencyBeginClaimsNewKey(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	client := rueidismock.NewClient(ctrl)
+	store := NewIdempotency[testPayload](client, "jobs", time.Minute, time.Hour)
+
+	client.EXPECT().
+		Do(ctx, matchSetCommand("jobs:run-1", func(tokens []string) bool {
+			return containsToken(tokens, "NX")
+		})).
+		Return(rueidismock.Result(rueidismock.RedisString("OK")))
+
+	result, token, err := store.Begin(ctx, "run-1")
+	if err != nil {
+		t.Fatalf("Begin returned error: %v", err)
+	}
+	if result != nil || token == "" {
+		t.Fatalf("expected a fresh claim, got %#v token=%q", result, token)
+	}
+}
+
+func TestIdempotencyCompleteAfterClaimLost(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	store := NewIdempotency[testPayload](nil, "jobs", time.Minute, time.Hour, WithStore(NewMemoryStore()))
+
+	_, stale, err := store.Begin(ctx, "run-1")
+	if err != nil {
+		t.Fatalf("Begin returned error: %v", err)
+	}
+
+	// The first claim expired and another worker claimed the key again.
+	if err := store.value.Delete(ctx, "run-1"); err != nil {
+		t.Fatalf("Delete returned error: %v", err)
+	}
+	_, token, err := store.Begin(ctx, "run-1")
+	if err != nil {
+		t.Fatalf("Begin returned error: %v", err)
+	}
+
+	if err := store.Complete(ctx, "run-1", stale, &testPayload{Message: "stale"}); !errors.Is(err, ErrClaimLost) {
+		t.Fatalf("expected ErrClaimLost, got %v", err)
+	}
+	if err := store.Abort(ctx, "run-1", stale); !errors.Is(err, ErrClaimLost) {
+		t.Fatalf("expected ErrClaimLost, got %v", err)
+	}
+
+	if err := store.Complete(ctx, "run-1", token, &testPayload{Message: "fresh"}); err != nil {
+		t.Fatalf("Complete returned error: %v", err)
+	}
+	result, token, err := store.Begin(ctx, "run-1")
+	if err != nil || token != "" || result.Message != "fresh" {
+		t.Fatalf("expected the fresh result to be replayed, got %+v token=%q err=%v", result, token, err)
+	}
+}
+
+// testScope identifies the caller by the X-User header.
+func testScope(r *http.Request) (string, error) {
+	user := r.Header.Get("X-User")
+	if user == "" {
+		return "", errors.New("unauthenticated")
+	}
+
+	return user, nil
+}
+
+func mustMarshal(t *testing.T, value any) []byte {
+	t.Helper()
+
+	data, err := cbor.Marshal(value)
+	if err != nil {
+		t.Fatalf("failed to encode fixture: %v", err)
+	}
+	return data
+}
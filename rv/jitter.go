@@ -0,0 +1,35 @@
+package rv
+
+import (
+	"math/rand/v2"
+	"time"
+)
+
+// WithTTLJitter randomizes the TTL of every Set call by up to fraction of it in either direction, e.g. 0.1
+// turns a 10 minute TTL into one between 9 and 11 minutes, so values written together do not all expire in the
+// same second. It applies to both WithDefaultExpiration and SetTTL. Fraction is clamped to [0, 1].
+func WithTTLJitter(fraction float64) Option {
+	return func(r *valueConfig) {
+		r.ttlJitter = fraction
+	}
+}
+
+// SetTTLJitter overrides the fraction configured with WithTTLJitter for a single Set call; 0 disables jitter.
+func SetTTLJitter(fraction float64) SetOption {
+	return func(o *setOption) {
+		o.Jitter = &fraction
+	}
+}
+
+// jitterTTL spreads ttl uniformly within ttl*(1±fraction). As Redis expirations are in whole milliseconds, the
+// result is never shortened below one millisecond.
+func jitterTTL(ttl time.Duration, fraction float64) time.Duration {
+	fraction = min(max(fraction, 0), 1)
+	if ttl <= 0 || fraction == 0 {
+		return ttl
+	}
+
+	jittered := time.Duration(float64(ttl) * (1 + fraction*(2*rand.Float64()-1)))
+
+	return max(jittered, min(ttl, time.Millisecond))
+}
@@ -0,0 +1,57 @@
+package rv
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestValueTTLJitterSpreadsExpirations(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	store := NewMemoryStore()
+	value := NewValue[testPayload](nil, nil, "warm", WithStore(store),
+		WithDefaultExpiration(100*time.Second), WithTTLJitter(0.2))
+
+	seen := make(map[time.Duration]struct{})
+	for i := range 50 {
+		key := fmt.Sprint(i)
+		if err := value.Set(ctx, key, &testPayload{Message: key}); err != nil {
+			t.Fatalf("Set returned error: %v", err)
+		}
+
+		ttl, err := store.TTL(ctx, "warm:"+key)
+		if err != nil {
+			t.Fatalf("TTL returned error: %v", err)
+		}
+		if ttl < 80*time.Second || ttl > 120*time.Second {
+			t.Fatalf("TTL %v is outside of the jitter range", ttl)
+		}
+		seen[ttl] = struct{}{}
+	}
+	if len(seen) < 2 {
+		t.Fatalf("expected jittered TTLs to differ, got %v", seen)
+	}
+
+	if err := value.Set(ctx, "exact", &testPayload{}, SetTTLJitter(0)); err != nil {
+		t.Fatalf("Set returned error: %v", err)
+	}
+	if ttl, _ := store.TTL(ctx, "warm:exact"); ttl != 100*time.Second {
+		t.Fatalf("expected SetTTLJitter(0) to disable jitter, got %v", ttl)
+	}
+}
+
+func TestJitterTTLBounds(t *testing.T) {
+	t.Parallel()
+
+	if got := jitterTTL(0, 0.5); got != 0 {
+		t.Fatalf("expected no expiration to stay unset, got %v", got)
+	}
+	for range 100 {
+		if got := jitterTTL(2*time.Second, 5); got < time.Millisecond || got > 4*time.Second {
+			t.Fatalf("jittered TTL %v is out of bounds", got)
+		}
+	}
+}
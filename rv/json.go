@@ -0,0 +1,302 @@
+package rv
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/redis/rueidis"
+)
+
+// ErrJSONUnsupported is returned, wrapped, by a JSONValue when the server does not have the RedisJSON module, and
+// by its path operations with WithJSONFallback.
+var ErrJSONUnsupported = errors.New("RedisJSON module is not available")
+
+// WithJSONFallback makes a JSONValue store documents as plain string keys holding their JSON encoding, for servers
+// without the RedisJSON module. Set, Get and Delete then go through the Store like those of Value, while the path
+// operations return ErrJSONUnsupported. Documents are not converted between the two formats, so a namespace must
+// always be used with or always without this option; SupportsJSON helps to decide once at startup.
+func WithJSONFallback() Option {
+	return func(r *valueConfig) {
+		r.jsonFallback = true
+	}
+}
+
+// SupportsJSON reports whether the server of client has the RedisJSON module.
+func SupportsJSON(ctx context.Context, client rueidis.Client) (bool, error) {
+	err := client.Do(ctx, client.B().JsonType().Key("rv:probe").Build()).Error()
+	if isUnknownCommand(err) {
+		return false, nil
+	}
+	if err != nil && !rueidis.IsRedisNil(err) {
+		return false, fmt.Errorf("failed to probe RedisJSON: %w", err)
+	}
+
+	return true, nil
+}
+
+// jsonSetScript stores the document at the root of KEYS[1], honoring the NX or XX condition in ARGV[2], and then
+// applies the TTL in milliseconds in ARGV[3], or removes any TTL unless ARGV[4] asks to keep it, like SET does.
+var jsonSetScript = rueidis.NewLuaScript(`
+local set
+if ARGV[2] == '' then
+	set = redis.call('JSON.SET', KEYS[1], '$', ARGV[1])
+else
+	set = redis.call('JSON.SET', KEYS[1], '$', ARGV[1], ARGV[2])
+end
+if not set then
+	return false
+end
+local ttl = tonumber(ARGV[3])
+if ttl > 0 then
+	redis.call('PEXPIRE', KEYS[1], ttl)
+elseif ARGV[4] == '0' then
+	redis.call('PERSIST', KEYS[1])
+end
+return 1
+`)
+
+// JSONValue is a typed wrapper around a namespaced Redis keyspace of RedisJSON documents. Unlike Value, which
+// stores opaque CBOR, it can read and update parts of a document with JSONPath expressions. Values are encoded
+// with encoding/json, so T is described by json struct tags. Servers without the RedisJSON module require
+// WithJSONFallback.
+type JSONValue[T any] struct {
+	client rueidis.Client
+	store  Store
+	key    string
+
+	config valueConfig
+}
+
+// NewJSONValue instantiates a JSONValue helper for the provided key prefix. WithDefaultExpiration,
+// WithTTLJitter and WithHashTag apply as they do to Value, and Delete, as well as Set and Get with
+// WithJSONFallback, go through the Store configured with WithStore and WithCircuitBreaker.
+func NewJSONValue[T any](client rueidis.Client, key string, options ...Option) *JSONValue[T] {
+	j := &JSONValue[T]{client: client, key: key}
+
+	for _, opt := range options {
+		opt(&j.config)
+	}
+
+	if j.config.hashTag {
+		j.key = "{" + key + "}"
+	}
+
+	j.store = j.config.newStore(client)
+
+	return j
+}
+
+// Set encodes and stores the provided value under the namespaced key, replacing the whole document.
+func (j *JSONValue[T]) Set(ctx context.Context, key string, value *T, setOptions ...SetOption) error {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("failed to encode value: %w", err)
+	}
+
+	args, err := j.config.setArgs(setOptions)
+	if err != nil {
+		return err
+	}
+
+	if j.config.jsonFallback {
+		err = j.store.Set(ctx, j.namespacedKey(key), encoded, args)
+	} else {
+		condition := ""
+		if args.NX {
+			condition = "NX"
+		} else if args.XX {
+			condition = "XX"
+		}
+
+		keepTTL := "0"
+		if args.KeepTTL {
+			keepTTL = "1"
+		}
+
+		err = jsonError(jsonSetScript.Exec(ctx, j.client, []string{j.namespacedKey(key)}, []string{
+			string(encoded), condition, strconv.FormatInt(args.TTL.Milliseconds(), 10), keepTTL,
+		}).Error())
+	}
+	if err != nil {
+		return fmt.Errorf("failed to set value: %w", err)
+	}
+
+	return nil
+}
+
+// Get loads a whole document by key.
+func (j *JSONValue[T]) Get(ctx context.Context, key string) (*T, error) {
+	var (
+		resp []byte
+		err  error
+	)
+	if j.config.jsonFallback {
+		resp, err = j.store.Get(ctx, j.namespacedKey(key))
+	} else {
+		resp, err = j.client.Do(ctx, j.client.B().JsonGet().Key(j.namespacedKey(key)).Build()).AsBytes()
+		err = jsonError(err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get value: %w", err)
+	}
+
+	var value T
+	if err := json.Unmarshal(resp, &value); err != nil {
+		return nil, fmt.Errorf("failed to decode value: %w", err)
+	}
+
+	return &value, nil
+}
+
+// Delete removes the namespaced key from Redis.
+func (j *JSONValue[T]) Delete(ctx context.Context, key string) error {
+	if _, err := j.store.Delete(ctx, j.namespacedKey(key)); err != nil {
+		return fmt.Errorf("failed to delete value: %w", err)
+	}
+
+	return nil
+}
+
+// GetPath decodes the values matching the JSONPath expression, such as "$.address.city", into dest. A JSONPath
+// can match several values, so dest receives a JSON array of all matches and is typically a pointer to a slice.
+// A missing key is reported as an error wrapping rueidis.Nil, while a path without matches yields an empty array.
+func (j *JSONValue[T]) GetPath(ctx context.Context, key, path string, dest any) error {
+	msg, err := j.do(ctx, j.client.B().JsonGet().Key(j.namespacedKey(key)).Path(path).Build())
+	if err != nil {
+		return fmt.Errorf("failed to get path %q: %w", path, err)
+	}
+
+	resp, err := msg.AsBytes()
+	if err != nil {
+		return fmt.Errorf("failed to get path %q: %w", path, err)
+	}
+
+	if err := json.Unmarshal(resp, dest); err != nil {
+		return fmt.Errorf("failed to decode path %q: %w", path, err)
+	}
+
+	return nil
+}
+
+// SetPath encodes value and stores it at every match of the JSONPath expression, adding it to an object if the
+// last path segment does not exist yet. If the parent of the path does not exist, it returns an error wrapping
+// rueidis.Nil. The key must already hold a document; SetPath leaves its TTL unchanged.
+func (j *JSONValue[T]) SetPath(ctx context.Context, key, path string, value any) error {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("failed to encode value: %w", err)
+	}
+
+	cmd := j.client.B().JsonSet().Key(j.namespacedKey(key)).Path(path).Value(string(encoded)).Build()
+	if _, err := j.do(ctx, cmd); err != nil {
+		return fmt.Errorf("failed to set path %q: %w", path, err)
+	}
+
+	return nil
+}
+
+// ArrAppend appends the encoded values to every array matching the JSONPath expression and returns the new
+// length of each match, or -1 for matches that are not arrays.
+func (j *JSONValue[T]) ArrAppend(ctx context.Context, key, path string, values ...any) ([]int64, error) {
+	encoded := make([]string, len(values))
+	for i, v := range values {
+		e, err := json.Marshal(v)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode value: %w", err)
+		}
+		encoded[i] = string(e)
+	}
+
+	msg, err := j.do(ctx, j.client.B().JsonArrappend().Key(j.namespacedKey(key)).Path(path).Value(encoded...).Build())
+	if err != nil {
+		return nil, fmt.Errorf("failed to append to path %q: %w", path, err)
+	}
+
+	resp, err := msg.ToArray()
+	if err != nil {
+		return nil, fmt.Errorf("failed to append to path %q: %w", path, err)
+	}
+
+	lengths := make([]int64, len(resp))
+	for i, msg := range resp {
+		if msg.IsNil() {
+			lengths[i] = -1
+			continue
+		}
+		if lengths[i], err = msg.AsInt64(); err != nil {
+			return nil, fmt.Errorf("failed to append to path %q: %w", path, err)
+		}
+	}
+
+	return lengths, nil
+}
+
+// NumIncrBy adds delta to every number matching the JSONPath expression and returns the new value of each match,
+// or NaN for matches that are not numbers.
+func (j *JSONValue[T]) NumIncrBy(ctx context.Context, key, path string, delta float64) ([]float64, error) {
+	msg, err := j.do(ctx, j.client.B().JsonNumincrby().Key(j.namespacedKey(key)).Path(path).Value(delta).Build())
+	if err != nil {
+		return nil, fmt.Errorf("failed to increment path %q: %w", path, err)
+	}
+
+	resp, err := msg.AsBytes()
+	if err != nil {
+		return nil, fmt.Errorf("failed to increment path %q: %w", path, err)
+	}
+
+	var matches []*float64
+	if err := json.Unmarshal(resp, &matches); err != nil {
+		return nil, fmt.Errorf("failed to decode path %q: %w", path, err)
+	}
+
+	numbers := make([]float64, len(matches))
+	for i, n := range matches {
+		if n == nil {
+			numbers[i] = math.NaN()
+			continue
+		}
+		numbers[i] = *n
+	}
+
+	return numbers, nil
+}
+
+// do runs a path operation, which is not available with WithJSONFallback.
+func (j *JSONValue[T]) do(ctx context.Context, cmd rueidis.Completed) (rueidis.RedisMessage, error) {
+	if j.config.jsonFallback {
+		return rueidis.RedisMessage{}, ErrJSONUnsupported
+	}
+
+	msg, err := j.client.Do(ctx, cmd).ToMessage()
+	return msg, jsonError(err)
+}
+
+// jsonError replaces the error of a server without the RedisJSON module with ErrJSONUnsupported.
+func jsonError(err error) error {
+	if isUnknownCommand(err) {
+		return ErrJSONUnsupported
+	}
+
+	return err
+}
+
+// isUnknownCommand reports whether err means the server does not know a command, typically because the module
+// providing it is not loaded.
+func isUnknownCommand(err error) bool {
+	var redisErr *rueidis.RedisError
+	if !errors.As(err, &redisErr) {
+		return false
+	}
+
+	msg := strings.ToLower(redisErr.Error())
+	return strings.Contains(msg, "unknown") && strings.Contains(msg, "command")
+}
+
+func (j *JSONValue[T]) namespacedKey(key string) string {
+	return j.key + ":" + key
+}
@@ -0,0 +1,183 @@
+package rv
+
+import (
+	"context"
+	"errors"
+	"math"
+	"slices"
+	"testing"
+	"time"
+
+	rueidismock "github.com/redis/rueidis/mock"
+	"go.uber.org/mock/gomock"
+)
+
+type testOrder struct {
+	ID    string   `json:"id"`
+	Items []string `json:"items"`
+	Total float64  `json:"total"`
+}
+
+func TestJSONValueSetAndGet(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	client := rueidismock.NewClient(ctrl)
+	orders := NewJSONValue[testOrder](client, "orders", WithDefaultExpiration(time.Hour))
+
+	document := `{"id":"42","items":["tea"],"total":3.5}`
+
+	client.EXPECT().
+		Do(ctx, rueidismock.MatchFn(func(tokens []string) bool {
+			return tokens[0] == "EVALSHA" && slices.Equal(tokens[3:], []string{"orders:42", document, "XX", "3600000", "0"})
+		}, "EVALSHA JSON.SET")).
+		Return(rueidismock.Result(rueidismock.RedisInt64(1)))
+	client.EXPECT().
+		Do(ctx, rueidismock.Match("JSON.GET", "orders:42")).
+		Return(rueidismock.Result(rueidismock.RedisBlobString(document)))
+
+	if err := orders.Set(ctx, "42", &testOrder{ID: "42", Items: []string{"tea"}, Total: 3.5}, SetXX(true)); err != nil {
+		t.Fatalf("Set returned error: %v", err)
+	}
+
+	got, err := orders.Get(ctx, "42")
+	if err != nil {
+		t.Fatalf("Get returned error: %v", err)
+	}
+	if got.ID != "42" || !slices.Equal(got.Items, []string{"tea"}) || got.Total != 3.5 {
+		t.Fatalf("unexpected document: %+v", got)
+	}
+}
+
+func TestJSONValuePathOperations(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	client := rueidismock.NewClient(ctrl)
+	orders := NewJSONValue[testOrder](client, "orders")
+
+	client.EXPECT().
+		Do(ctx, rueidismock.Match("JSON.GET", "orders:42", "$.items")).
+		Return(rueidismock.Result(rueidismock.RedisBlobString(`[["tea"]]`)))
+	client.EXPECT().
+		Do(ctx, rueidismock.Match("JSON.SET", "orders:42", "$.total", "4")).
+		Return(rueidismock.Result(rueidismock.RedisString("OK")))
+	client.EXPECT().
+		Do(ctx, rueidismock.Match("JSON.ARRAPPEND", "orders:42", "$..items", `"milk"`, `"sugar"`)).
+		Return(rueidismock.Result(rueidismock.RedisArray(rueidismock.RedisInt64(3), rueidismock.RedisNil())))
+	client.EXPECT().
+		Do(ctx, rueidismock.Match("JSON.NUMINCRBY", "orders:42", "$..total", "1.5")).
+		Return(rueidismock.Result(rueidismock.RedisBlobString(`[5.5,null]`)))
+
+	var items [][]string
+	if err := orders.GetPath(ctx, "42", "$.items", &items); err != nil {
+		t.Fatalf("GetPath returned error: %v", err)
+	}
+	if len(items) != 1 || !slices.Equal(items[0], []string{"tea"}) {
+		t.Fatalf("unexpected matches: %v", items)
+	}
+
+	if err := orders.SetPath(ctx, "42", "$.total", 4); err != nil {
+		t.Fatalf("SetPath returned error: %v", err)
+	}
+
+	lengths, err := orders.ArrAppend(ctx, "42", "$..items", "milk", "sugar")
+	if err != nil {
+		t.Fatalf("ArrAppend returned error: %v", err)
+	}
+	if !slices.Equal(lengths, []int64{3, -1}) {
+		t.Fatalf("unexpected lengths: %v", lengths)
+	}
+
+	totals, err := orders.NumIncrBy(ctx, "42", "$..total", 1.5)
+	if err != nil {
+		t.Fatalf("NumIncrBy returned error: %v", err)
+	}
+	if len(totals) != 2 || totals[0] != 5.5 || !math.IsNaN(totals[1]) {
+		t.Fatalf("unexpected totals: %v", totals)
+	}
+}
+
+func TestJSONValueWithFallbackUsesStore(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := NewMemoryStore()
+	orders := NewJSONValue[testOrder](rueidismock.NewClient(ctrl), "orders", WithJSONFallback(), WithStore(store))
+
+	if err := orders.Set(ctx, "42", &testOrder{ID: "42"}); err != nil {
+		t.Fatalf("Set returned error: %v", err)
+	}
+	if raw, err := store.Get(ctx, "orders:42"); err != nil || string(raw) != `{"id":"42","items":null,"total":0}` {
+		t.Fatalf("expected the encoded document in the store, got %q, %v", raw, err)
+	}
+
+	got, err := orders.Get(ctx, "42")
+	if err != nil || got.ID != "42" {
+		t.Fatalf("expected the document from the fallback, got %v, %v", got, err)
+	}
+
+	if _, err := orders.NumIncrBy(ctx, "42", "$.total", 1); !errors.Is(err, ErrJSONUnsupported) {
+		t.Fatalf("expected ErrJSONUnsupported, got %v", err)
+	}
+}
+
+func TestJSONValueWithoutModuleDoesNotFallBack(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	client := rueidismock.NewClient(ctrl)
+	orders := NewJSONValue[testOrder](client, "orders")
+
+	unknown := rueidismock.Result(rueidismock.RedisError("ERR unknown command 'JSON.GET', with args beginning with: "))
+	client.EXPECT().
+		Do(ctx, matchEvalshaCommand("orders:42")).
+		Return(rueidismock.Result(rueidismock.RedisError("ERR Error running script: Unknown Redis command called from script")))
+	client.EXPECT().Do(ctx, rueidismock.Match("JSON.GET", "orders:42")).Return(unknown)
+	client.EXPECT().Do(ctx, rueidismock.Match("JSON.GET", "orders:42")).Return(unknown)
+
+	if err := orders.Set(ctx, "42", &testOrder{ID: "42"}); !errors.Is(err, ErrJSONUnsupported) {
+		t.Fatalf("expected ErrJSONUnsupported from Set, got %v", err)
+	}
+	for range 2 {
+		if _, err := orders.Get(ctx, "42"); !errors.Is(err, ErrJSONUnsupported) {
+			t.Fatalf("expected ErrJSONUnsupported from Get, got %v", err)
+		}
+	}
+}
+
+func TestSupportsJSON(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	client := rueidismock.NewClient(ctrl)
+
+	client.EXPECT().
+		Do(ctx, rueidismock.Match("JSON.TYPE", "rv:probe")).
+		Return(rueidismock.Result(rueidismock.RedisNil()))
+	client.EXPECT().
+		Do(ctx, rueidismock.Match("JSON.TYPE", "rv:probe")).
+		Return(rueidismock.Result(rueidismock.RedisError("ERR unknown command 'JSON.TYPE', with args beginning with: ")))
+
+	if ok, err := SupportsJSON(ctx, client); err != nil || !ok {
+		t.Fatalf("expected support, got %v, %v", ok, err)
+	}
+	if ok, err := SupportsJSON(ctx, client); err != nil || ok {
+		t.Fatalf("expected no support, got %v, %v", ok, err)
+	}
+}
@@ -0,0 +1,135 @@
+package rv
+
+import (
+	"encoding"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// keyEscaper percent-encodes the key separator, Redis glob metacharacters and hash tag braces, so a key part
+// can neither split into several segments nor widen a Scan pattern.
+var keyEscaper = strings.NewReplacer(
+	"%", "%25",
+	":", "%3A",
+	"*", "%2A",
+	"?", "%3F",
+	"[", "%5B",
+	"]", "%5D",
+	"\\", "%5C",
+	"{", "%7B",
+	"}", "%7D",
+)
+
+// Glob is a key part that is passed through unescaped, so it can carry wildcards in Scan, DeleteMatching or
+// ExpireMatching patterns.
+type Glob string
+
+// EscapeKey escapes a single key part the same way Key does.
+func EscapeKey(part string) string {
+	return keyEscaper.Replace(part)
+}
+
+// Key composes a key from typed parts joined by ':'. Every part is escaped with EscapeKey except Glob.
+//
+// Strings and byte slices are used as-is, integers, floats and booleans are formatted in their shortest
+// decimal form, and fmt.Stringer and encoding.TextMarshaler implementations, such as u22.EncodedID, are
+// formatted through their own method. Any other type is formatted with fmt.Sprint.
+func Key(parts ...any) string {
+	var b strings.Builder
+	for i, part := range parts {
+		if i > 0 {
+			b.WriteByte(':')
+		}
+
+		if glob, ok := part.(Glob); ok {
+			b.WriteString(string(glob))
+			continue
+		}
+
+		b.WriteString(EscapeKey(formatKeyPart(part)))
+	}
+
+	return b.String()
+}
+
+func formatKeyPart(part any) string {
+	switch v := part.(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	case bool:
+		return strconv.FormatBool(v)
+	case int:
+		return strconv.FormatInt(int64(v), 10)
+	case int8:
+		return strconv.FormatInt(int64(v), 10)
+	case int16:
+		return strconv.FormatInt(int64(v), 10)
+	case int32:
+		return strconv.FormatInt(int64(v), 10)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case uint:
+		return strconv.FormatUint(uint64(v), 10)
+	case uint8:
+		return strconv.FormatUint(uint64(v), 10)
+	case uint16:
+		return strconv.FormatUint(uint64(v), 10)
+	case uint32:
+		return strconv.FormatUint(uint64(v), 10)
+	case uint64:
+		return strconv.FormatUint(v, 10)
+	case float32:
+		return strconv.FormatFloat(float64(v), 'g', -1, 32)
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	case fmt.Stringer:
+		return v.String()
+	case encoding.TextMarshaler:
+		text, err := v.MarshalText()
+		if err != nil {
+			return fmt.Sprint(part)
+		}
+		return string(text)
+	default:
+		return fmt.Sprint(part)
+	}
+}
+
+// WithHashTag wraps the namespace in a Redis hash tag, storing keys as "{prefix}:key", so every key of the
+// Value, including its sub-namespaces, hashes to the same Redis Cluster slot. This allows multi-key
+// commands, transactions and scripts across the namespace, at the cost of placing it on a single shard.
+func WithHashTag() Option {
+	return func(r *valueConfig) {
+		r.hashTag = true
+	}
+}
+
+// HashTag returns a key part that Key wraps in a Redis hash tag, e.g. Key("user", HashTag(id), "cart")
+// yields "user:{id}:cart", so keys sharing the part are co-located in one Redis Cluster slot. The part is
+// escaped like any other. Redis only honors the first hash tag of a key, so it has no effect inside a
+// namespace configured with WithHashTag.
+func HashTag(part any) Glob {
+	return Glob("{" + EscapeKey(formatKeyPart(part)) + "}")
+}
+
+// internalKey returns the key of the bookkeeping of kind, such as chunks or lock holder records, kept for rawKey.
+// It lives under "prefix~kind:" beside the namespace rather than inside it, so no key of the Value can collide with
+// it, and it keeps the hash tag of rawKey.
+func (r *Value[T]) internalKey(kind, rawKey string) string {
+	return r.key + "~" + kind + ":" + strings.TrimPrefix(rawKey, r.namespacedKey(""))
+}
+
+// Sub returns a Value of the same type scoped to the sub-namespace "prefix:segment". The segment is escaped
+// with EscapeKey, so it always forms exactly one level of the hierarchy. The sub-namespace shares the client,
+// locker, store and options of r. Its metrics are recorded under the namespace of r, so segments such as user
+// IDs do not multiply metric series, while spans also carry the sub-namespace.
+func (r *Value[T]) Sub(segment string) *Value[T] {
+	sub := *r
+	sub.key = r.namespacedKey(EscapeKey(segment))
+	sub.telemetry = r.telemetry.sub(sub.key)
+
+	return &sub
+}
@@ -0,0 +1,102 @@
+package rv
+
+import (
+	"context"
+	"net/netip"
+	"testing"
+
+	rueidismock "github.com/redis/rueidis/mock"
+	"go.uber.org/mock/gomock"
+)
+
+type testTenantID string
+
+func (id testTenantID) String() string {
+	return "tenant-" + string(id)
+}
+
+func TestKeyEscapesParts(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		parts []any
+		want  string
+	}{
+		{name: "plain", parts: []any{"user", 42, true}, want: "user:42:true"},
+		{name: "separator", parts: []any{"a:b", "c"}, want: "a%3Ab:c"},
+		{name: "glob metacharacters", parts: []any{"*?[x]\\"}, want: "%2A%3F%5Bx%5D%5C"},
+		{name: "hash tag braces", parts: []any{"{slot}"}, want: "%7Bslot%7D"},
+		{name: "percent", parts: []any{"100%"}, want: "100%25"},
+		{name: "stringer", parts: []any{testTenantID("a:b")}, want: "tenant-a%3Ab"},
+		{name: "text marshaler", parts: []any{netip.MustParseAddr("::1")}, want: "%3A%3A1"},
+		{name: "float", parts: []any{1.5}, want: "1.5"},
+		{name: "glob", parts: []any{"orders", Glob("*")}, want: "orders:*"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := Key(tt.parts...); got != tt.want {
+				t.Fatalf("Key(%v) = %q, want %q", tt.parts, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValueSubScopesNamespace(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	store := NewMemoryStore()
+	root := NewValue[testPayload](nil, nil, "app", WithStore(store))
+	tenant := root.Sub("acme").Sub("orders:*")
+
+	if err := tenant.Set(ctx, Key("order", 1), &testPayload{Message: "first"}); err != nil {
+		t.Fatalf("Set returned error: %v", err)
+	}
+	if err := root.Set(ctx, "orders", &testPayload{Message: "outside"}); err != nil {
+		t.Fatalf("Set returned error: %v", err)
+	}
+
+	keys := store.Keys()
+	if len(keys) != 2 || keys[0] != "app:acme:orders%3A%2A:order:1" || keys[1] != "app:orders" {
+		t.Fatalf("unexpected keys %v", keys)
+	}
+
+	values, err := tenant.Scan(ctx, Key("order", Glob("*")))
+	if err != nil {
+		t.Fatalf("Scan returned error: %v", err)
+	}
+	if len(values) != 1 || values[0].Message != "first" {
+		t.Fatalf("expected only the sub-namespace value, got %v", values)
+	}
+}
+
+func TestValueWithHashTagColocatesKeys(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	client := rueidismock.NewClient(ctrl, rueidismock.WithSlotCheck())
+	value := NewValue[testPayload](client, nil, "cart", WithHashTag())
+	items := value.Sub("items")
+
+	keys := []string{value.namespacedKey("a"), value.namespacedKey("b"), items.namespacedKey(Key("user", 1))}
+	if keys[0] != "{cart}:a" || keys[2] != "{cart}:items:user:1" {
+		t.Fatalf("unexpected hash-tagged keys %v", keys)
+	}
+
+	// The slot checking builder panics if the keys span several slots.
+	client.B().Mget().Key(keys...).Build()
+}
+
+func TestKeyHashTagPart(t *testing.T) {
+	t.Parallel()
+
+	if got := Key("user", HashTag("a{b}"), "cart"); got != "user:{a%7Bb%7D}:cart" {
+		t.Fatalf("unexpected key %q", got)
+	}
+}
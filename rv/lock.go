@@ -0,0 +1,292 @@
+package rv
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/redis/rueidis"
+	"github.com/redis/rueidis/rueidislock"
+)
+
+// ErrLockNotAcquired is returned, wrapped, by TryWithLock when the lock is held elsewhere and by WithLock when
+// the lock was not acquired within WithLockTimeout.
+var ErrLockNotAcquired = errors.New("lock not acquired")
+
+// lockInfoTag marks the holder record written next to a held lock, encoding as the fixed three-byte prefix
+// lockInfoPrefix.
+const lockInfoTag = 0x727a
+
+var lockInfoPrefix = []byte{0xd9, 0x72, 0x7a}
+
+// lockInfoTTL bounds how long a holder record outlives a holder that died without releasing its lock. It is
+// refreshed at half that interval while the lock is held.
+const lockInfoTTL = 5 * time.Second
+
+// LockInfo describes a held lock and its holder.
+type LockInfo struct {
+	// Holder identifies the process holding the lock, or is empty if it did not enable WithLockHolder.
+	Holder string
+	// Acquired is when the holder acquired the lock, or is zero if it did not enable WithLockHolder.
+	Acquired time.Time
+	// Expires is when the lock expires unless its holder extends it, which the locker does periodically while
+	// the lock is held.
+	Expires time.Time
+}
+
+type lockRecord struct {
+	Holder   string `cbor:"0,keyasint"`
+	Acquired int64  `cbor:"1,keyasint"` // Unix milliseconds
+}
+
+// WithLockTimeout limits how long WithLock waits for a held lock before giving up with ErrLockNotAcquired.
+// It does not limit how long the lock is held once acquired.
+func WithLockTimeout(timeout time.Duration) Option {
+	return func(r *valueConfig) {
+		r.lockTimeout = timeout
+	}
+}
+
+// WithLockKeys tells LockInfo where the locker keeps its locks, and must match the KeyPrefix and KeyMajority of
+// the rueidislock.LockerOption the locker was created with. By default, LockInfo assumes the defaults of
+// rueidislock, the "rueidislock" prefix and a majority of 2.
+func WithLockKeys(prefix string, majority int) Option {
+	return func(r *valueConfig) {
+		r.lockKeyPrefix = prefix
+		r.lockKeyMajority = majority
+	}
+}
+
+// WithLockHolder records the holder of locks acquired through this Value, so LockInfo can report it. The holder
+// is identified by holder, or by the host name and process ID, like an Election candidate, if it is empty.
+// Recording costs a write when the lock is acquired, a refresh every few seconds while it is held and a delete on
+// release, so it is off by default.
+func WithLockHolder(holder string) Option {
+	return func(r *valueConfig) {
+		r.recordLockHolder = true
+		r.lockHolder = holder
+	}
+}
+
+// WithLock acquires a distributed lock for the given key and executes the provided function within the lock's context.
+// It does not mean that the key itself is locked, but rather a namespaced lock based on the provided key.
+func (r *Value[T]) WithLock(ctx context.Context, key string, fn func(ctx context.Context) error) (err error) {
+	ctx, op := r.telemetry.start(ctx, "lock")
+	defer func() { op.end(err) }()
+
+	ctx, release, err := r.acquireLock(ctx, key, false)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	return fn(ctx)
+}
+
+// TryWithLock is WithLock without waiting: if the lock is held elsewhere, it returns an error wrapping
+// ErrLockNotAcquired and rueidislock.ErrNotLocked without calling fn.
+func (r *Value[T]) TryWithLock(ctx context.Context, key string, fn func(ctx context.Context) error) (err error) {
+	ctx, op := r.telemetry.start(ctx, "try_lock")
+	defer func() { op.end(err) }()
+
+	ctx, release, err := r.acquireLock(ctx, key, true)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	return fn(ctx)
+}
+
+// LockInfo returns the expiry of the lock for the given key and, if its holder enabled WithLockHolder, the holder.
+// It reads the TTLs of the locker's keys, see WithLockKeys, and returns an error wrapping rueidis.Nil if the lock
+// is not held.
+func (r *Value[T]) LockInfo(ctx context.Context, key string) (*LockInfo, error) {
+	name := r.namespacedKey(key)
+
+	prefix, majority := r.config.lockKeyPrefix, r.config.lockKeyMajority
+	if prefix == "" {
+		prefix = "rueidislock"
+	}
+	if majority <= 0 {
+		majority = 2
+	}
+
+	// The locker holds the lock through at least majority of its 2*majority-1 keys, so it expires once the
+	// majority-th longest of their TTLs runs out.
+	lockKeys := make([]string, 2*majority-1)
+	for i := range lockKeys {
+		lockKeys[i] = prefix + ":" + strconv.Itoa(i) + ":" + name
+	}
+
+	ttls, err := r.store.TTLs(ctx, lockKeys)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get lock info: %w", err)
+	}
+
+	var remaining []time.Duration
+	for _, ttl := range ttls {
+		if ttl > 0 {
+			remaining = append(remaining, ttl)
+		}
+	}
+	if len(remaining) < majority {
+		return nil, fmt.Errorf("failed to get lock info: %w", rueidis.Nil)
+	}
+	slices.Sort(remaining)
+
+	info := &LockInfo{Expires: time.Now().Add(remaining[len(remaining)-majority])}
+
+	data, err := r.store.Get(ctx, r.internalKey("lock", name))
+	if errors.Is(err, rueidis.Nil) {
+		return info, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get lock info: %w", err)
+	}
+	if !bytes.HasPrefix(data, lockInfoPrefix) {
+		return nil, errors.New("failed to get lock info: not a lock record")
+	}
+
+	var record lockRecord
+	if err := cbor.Unmarshal(data[len(lockInfoPrefix):], &record); err != nil {
+		return nil, fmt.Errorf("failed to decode lock info: %w", err)
+	}
+	info.Holder, info.Acquired = record.Holder, time.UnixMilli(record.Acquired)
+
+	return info, nil
+}
+
+// acquireLock acquires the lock for key and records its holder if WithLockHolder is set. The returned release
+// func drops both.
+func (r *Value[T]) acquireLock(ctx context.Context, key string, try bool) (context.Context, context.CancelFunc, error) {
+	name := r.namespacedKey(key)
+
+	var (
+		lockCtx context.Context
+		release context.CancelFunc
+		err     error
+	)
+	switch {
+	case try:
+		lockCtx, release, err = r.locker.TryWithContext(ctx, name)
+		if errors.Is(err, rueidislock.ErrNotLocked) {
+			return nil, nil, fmt.Errorf("%w for %q: %w", ErrLockNotAcquired, key, err)
+		}
+	case r.config.lockTimeout > 0:
+		lockCtx, release, err = r.waitLock(ctx, name)
+		if errors.Is(err, errLockTimeout) {
+			return nil, nil, fmt.Errorf("%w for %q within %v: %w", ErrLockNotAcquired, key, r.config.lockTimeout, context.DeadlineExceeded)
+		}
+	default:
+		lockCtx, release, err = r.locker.WithContext(ctx, name)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to acquire lock: %w", err)
+	}
+
+	if !r.config.recordLockHolder {
+		return lockCtx, release, nil
+	}
+
+	stop := r.recordHolder(lockCtx, r.internalKey("lock", name))
+
+	return lockCtx, func() {
+		stop()
+		release()
+	}, nil
+}
+
+var errLockTimeout = errors.New("lock timeout")
+
+// waitLock waits for the lock until WithLockTimeout elapses. The timeout only applies while waiting, so unlike
+// a context deadline it does not cancel the lock's context once acquired.
+func (r *Value[T]) waitLock(ctx context.Context, name string) (context.Context, context.CancelFunc, error) {
+	waitCtx, cancel := context.WithCancelCause(ctx)
+	timer := time.AfterFunc(r.config.lockTimeout, func() { cancel(errLockTimeout) })
+
+	lockCtx, release, err := r.locker.WithContext(waitCtx, name)
+	if !timer.Stop() && err == nil {
+		// The timeout fired just as the lock was acquired, canceling it.
+		release()
+		err = waitCtx.Err()
+	}
+	if err != nil {
+		cancel(nil)
+		if errors.Is(context.Cause(waitCtx), errLockTimeout) {
+			return nil, nil, errLockTimeout
+		}
+		return nil, nil, err
+	}
+
+	return lockCtx, func() {
+		release()
+		cancel(nil)
+	}, nil
+}
+
+// recordHolder writes the holder record under infoKey and keeps it from expiring until the returned func is
+// called, which deletes it. The record is informational, so failing to write it does not fail the lock.
+func (r *Value[T]) recordHolder(lockCtx context.Context, infoKey string) func() {
+	record, err := cbor.Marshal(cbor.Tag{Number: lockInfoTag, Content: lockRecord{
+		Holder:   r.lockHolder(),
+		Acquired: time.Now().UnixMilli(),
+	}})
+	if err != nil {
+		return func() {}
+	}
+
+	ctx := context.WithoutCancel(lockCtx)
+	if err := r.store.Set(ctx, infoKey, record, SetArgs{TTL: lockInfoTTL}); err != nil {
+		return func() {}
+	}
+
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+
+		ticker := time.NewTicker(lockInfoTTL / 2)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-lockCtx.Done():
+				return // lost the lock; the record expires on its own
+			case <-ticker.C:
+				_, _ = r.store.Expire(ctx, lockInfoTTL, infoKey)
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		<-stopped
+
+		// After losing the lock, the record may already belong to the next holder.
+		if lockCtx.Err() == nil {
+			_, _ = r.store.Delete(ctx, infoKey)
+		}
+	}
+}
+
+// lockHolder returns the identity configured with WithLockHolder, defaulting to that of an Election candidate.
+func (r *Value[T]) lockHolder() string {
+	if r.config.lockHolder != "" {
+		return r.config.lockHolder
+	}
+
+	return defaultIdentity()
+}
+
+// isLockInfo reports whether data is a holder record written by WithLock.
+func isLockInfo(data []byte) bool {
+	return bytes.HasPrefix(data, lockInfoPrefix)
+}
@@ -0,0 +1,153 @@
+package rv
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/redis/rueidis"
+	"github.com/redis/rueidis/rueidislock"
+)
+
+func TestValueTryWithLockFailsFastWhileHeld(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	locker := NewMemoryLocker()
+	store := NewMemoryStore()
+	holder := NewValue[testPayload](nil, locker, "jobs", WithStore(store), WithLockHolder("pod-a"))
+	other := NewValue[testPayload](nil, locker, "jobs", WithStore(store), WithLockHolder("pod-b"))
+
+	err := holder.TryWithLock(ctx, "nightly", func(ctx context.Context) error {
+		holdLockKeys(t, store, "jobs:nightly", 4*time.Second, 5*time.Second)
+
+		info, err := other.LockInfo(ctx, "nightly")
+		if err != nil {
+			t.Fatalf("LockInfo returned error: %v", err)
+		}
+		if info.Holder != "pod-a" || time.Since(info.Acquired) > time.Minute {
+			t.Fatalf("unexpected lock info: %+v", info)
+		}
+		if expires := time.Until(info.Expires); expires <= 3*time.Second || expires > 4*time.Second {
+			t.Fatalf("expected the lock to expire with the second longest key, got %v", expires)
+		}
+
+		called := false
+		err = other.TryWithLock(ctx, "nightly", func(context.Context) error {
+			called = true
+			return nil
+		})
+		if !errors.Is(err, ErrLockNotAcquired) || !errors.Is(err, rueidislock.ErrNotLocked) {
+			t.Fatalf("expected ErrLockNotAcquired, got %v", err)
+		}
+		if called {
+			t.Fatal("expected fn not to be called without the lock")
+		}
+
+		values, err := other.Scan(ctx, "")
+		if err != nil || len(values) != 0 {
+			t.Fatalf("expected the holder record to be skipped by Scan, got %v, %v", values, err)
+		}
+
+		_, _ = store.Delete(ctx, "rueidislock:0:jobs:nightly", "rueidislock:1:jobs:nightly")
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("TryWithLock returned error: %v", err)
+	}
+
+	if _, err := other.LockInfo(ctx, "nightly"); !errors.Is(err, rueidis.Nil) {
+		t.Fatalf("expected rueidis.Nil after release, got %v", err)
+	}
+}
+
+func TestValueWithLockTimeout(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	locker := NewMemoryLocker()
+	value := NewValue[testPayload](nil, locker, "jobs", WithStore(NewMemoryStore()), WithLockTimeout(20*time.Millisecond))
+
+	_, release, err := locker.TryWithContext(ctx, "jobs:nightly")
+	if err != nil {
+		t.Fatalf("TryWithContext returned error: %v", err)
+	}
+
+	err = value.WithLock(ctx, "nightly", func(context.Context) error { return nil })
+	if !errors.Is(err, ErrLockNotAcquired) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected ErrLockNotAcquired after the timeout, got %v", err)
+	}
+
+	release()
+
+	err = value.WithLock(ctx, "nightly", func(ctx context.Context) error {
+		time.Sleep(40 * time.Millisecond)
+		return ctx.Err()
+	})
+	if err != nil {
+		t.Fatalf("expected the lock to outlive the timeout once acquired, got %v", err)
+	}
+}
+
+func TestValueWithLockSkipsHolderRecordByDefault(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	store := NewMemoryStore()
+	value := NewValue[testPayload](nil, NewMemoryLocker(), "jobs", WithStore(store))
+
+	err := value.WithLock(ctx, "nightly", func(ctx context.Context) error {
+		if keys := store.Keys(); len(keys) != 0 {
+			t.Fatalf("expected no holder record without WithLockHolder, got %v", keys)
+		}
+		if _, err := value.LockInfo(ctx, "nightly"); !errors.Is(err, rueidis.Nil) {
+			t.Fatalf("expected rueidis.Nil without the locker's keys, got %v", err)
+		}
+
+		holdLockKeys(t, store, "jobs:nightly", time.Second, time.Second)
+		info, err := value.LockInfo(ctx, "nightly")
+		if err != nil || info.Holder != "" || info.Expires.IsZero() {
+			t.Fatalf("expected the expiry without a holder, got %+v, %v", info, err)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("WithLock returned error: %v", err)
+	}
+}
+
+func TestValueWithLockKeepsHolderRecordOutsideNamespace(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	value := NewValue[testPayload](nil, NewMemoryLocker(), "jobs", WithStore(NewMemoryStore()), WithLockHolder("pod-a"))
+
+	if err := value.Set(ctx, "nightly~lock", &testPayload{Message: "user data"}); err != nil {
+		t.Fatalf("Set returned error: %v", err)
+	}
+
+	err := value.WithLock(ctx, "nightly", func(context.Context) error { return nil })
+	if err != nil {
+		t.Fatalf("WithLock returned error: %v", err)
+	}
+
+	got, err := value.Get(ctx, "nightly~lock")
+	if err != nil || got.Message != "user data" {
+		t.Fatalf("expected the value to survive the lock, got %v, %v", got, err)
+	}
+}
+
+// holdLockKeys simulates the keys rueidislock holds for name with its default options, one of which already
+// expired.
+func holdLockKeys(t *testing.T, store *MemoryStore, name string, first, second time.Duration) {
+	t.Helper()
+
+	ctx := context.Background()
+	for i, ttl := range []time.Duration{first, second} {
+		if err := store.Set(ctx, "rueidislock:"+strconv.Itoa(i)+":"+name, []byte("token"), SetArgs{TTL: ttl}); err != nil {
+			t.Fatalf("Set returned error: %v", err)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package rv
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// defaultMatchBatchSize is the SCAN COUNT hint used by DeleteMatching and ExpireMatching.
+const defaultMatchBatchSize = 500
+
+type matchOption struct {
+	dryRun    bool
+	batchSize int64
+	progress  func(processed int64)
+}
+
+type MatchOption func(*matchOption)
+
+// MatchDryRun only counts the matching keys without modifying them.
+func MatchDryRun() MatchOption {
+	return func(o *matchOption) {
+		o.dryRun = true
+	}
+}
+
+// MatchBatchSize sets the SCAN COUNT hint, which bounds how many keys are handled per pipelined batch.
+func MatchBatchSize(size int64) MatchOption {
+	return func(o *matchOption) {
+		o.batchSize = size
+	}
+}
+
+// MatchProgress registers a callback invoked after every batch with the number of keys processed so far.
+func MatchProgress(fn func(processed int64)) MatchOption {
+	return func(o *matchOption) {
+		o.progress = fn
+	}
+}
+
+// DeleteMatching removes every namespaced key matching the pattern (without the namespace prefix) using
+// pipelined UNLINK batches, and returns how many keys were removed. Passing an empty pattern matches all
+// keys in the namespace. With MatchDryRun it returns how many keys match instead. With WithChunking, the chunks
+// of matching values are removed too, without being counted.
+func (r *Value[T]) DeleteMatching(ctx context.Context, pattern string, options ...MatchOption) (_ int64, err error) {
+	ctx, op := r.telemetry.start(ctx, "delete_matching")
+	defer func() { op.end(err) }()
+
+	return r.forEachMatching(ctx, pattern, options, func(keys []string) (int64, error) {
+		chunks, err := r.matchedChunks(ctx, keys)
+		if err != nil {
+			return 0, fmt.Errorf("failed to delete values matching %q: %w", pattern, err)
+		}
+
+		removed, err := r.store.Unlink(ctx, keys...)
+		if err == nil && len(chunks) > 0 {
+			_, err = r.store.Unlink(ctx, chunks...)
+		}
+		if err != nil {
+			return removed, fmt.Errorf("failed to delete values matching %q: %w", pattern, err)
+		}
+		return removed, nil
+	})
+}
+
+// ExpireMatching sets the TTL of every namespaced key matching the pattern (without the namespace prefix)
+// using pipelined batches, and returns how many keys were updated. Passing an empty pattern matches all
+// keys in the namespace. With MatchDryRun it returns how many keys match instead. With WithChunking, the chunks
+// of matching values are updated too, without being counted.
+func (r *Value[T]) ExpireMatching(ctx context.Context, pattern string, ttl time.Duration, options ...MatchOption) (_ int64, err error) {
+	ctx, op := r.telemetry.start(ctx, "expire_matching")
+	defer func() { op.end(err) }()
+
+	return r.forEachMatching(ctx, pattern, options, func(keys []string) (int64, error) {
+		chunks, err := r.matchedChunks(ctx, keys)
+		if err != nil {
+			return 0, fmt.Errorf("failed to expire values matching %q: %w", pattern, err)
+		}
+
+		updated, err := r.store.Expire(ctx, ttl, keys...)
+		if err == nil && len(chunks) > 0 {
+			_, err = r.store.Expire(ctx, ttl, chunks...)
+		}
+		if err != nil {
+			return updated, fmt.Errorf("failed to expire values matching %q: %w", pattern, err)
+		}
+		return updated, nil
+	})
+}
+
+// forEachMatching applies fn to every batch of matching keys and tracks the number of affected keys.
+func (r *Value[T]) forEachMatching(ctx context.Context, pattern string, options []MatchOption, fn func(keys []string) (int64, error)) (int64, error) {
+	config := matchOption{batchSize: defaultMatchBatchSize}
+	for _, opt := range options {
+		opt(&config)
+	}
+
+	var processed int64
+	err := r.scanKeys(ctx, pattern, config.batchSize, func(keys []string) error {
+		if config.dryRun {
+			processed += int64(len(keys))
+		} else {
+			affected, err := fn(keys)
+			processed += affected
+			if err != nil {
+				return err
+			}
+		}
+
+		if config.progress != nil {
+			config.progress(processed)
+		}
+
+		return nil
+	})
+
+	return processed, err
+}
@@ -0,0 +1,94 @@
+package rv
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/redis/rueidis"
+	rueidismock "github.com/redis/rueidis/mock"
+	"go.uber.org/mock/gomock"
+)
+
+func TestValueDeleteMatchingUnlinksInPipelinedBatches(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	client := rueidismock.NewClient(ctrl)
+	value := NewValue[testPayload](client, nil, "purge")
+
+	gomock.InOrder(
+		client.EXPECT().
+			Do(ctx, rueidismock.Match("SCAN", "0", "MATCH", "purge:tmp:*", "COUNT", "500")).
+			Return(rueidismock.Result(scanResponse(7, []string{"purge:tmp:1", "purge:tmp:2"}))),
+		client.EXPECT().
+			DoMulti(ctx,
+				rueidismock.Match("UNLINK", "purge:tmp:1"),
+				rueidismock.Match("UNLINK", "purge:tmp:2"),
+			).
+			Return([]rueidis.RedisResult{
+				rueidismock.Result(rueidismock.RedisInt64(1)),
+				rueidismock.Result(rueidismock.RedisInt64(0)),
+			}),
+		client.EXPECT().
+			Do(ctx, rueidismock.Match("SCAN", "7", "MATCH", "purge:tmp:*", "COUNT", "500")).
+			Return(rueidismock.Result(scanResponse(0, []string{"purge:tmp:3"}))),
+		client.EXPECT().
+			DoMulti(ctx, rueidismock.Match("UNLINK", "purge:tmp:3")).
+			Return([]rueidis.RedisResult{
+				rueidismock.Result(rueidismock.RedisInt64(1)),
+			}),
+	)
+
+	var progress []int64
+	removed, err := value.DeleteMatching(ctx, "tmp:*", MatchProgress(func(processed int64) {
+		progress = append(progress, processed)
+	}))
+	if err != nil {
+		t.Fatalf("DeleteMatching returned error: %v", err)
+	}
+	if removed != 2 {
+		t.Fatalf("expected 2 removed keys, got %d", removed)
+	}
+	if len(progress) != 2 || progress[0] != 1 || progress[1] != 2 {
+		t.Fatalf("unexpected progress reports %v", progress)
+	}
+}
+
+func TestValueMatchingDryRunAndExpire(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	store := NewMemoryStore()
+	value := NewValue[testPayload](nil, nil, "bulk", WithStore(store))
+
+	for _, key := range []string{"a:1", "a:2", "b:1"} {
+		if err := value.Set(ctx, key, &testPayload{Message: key}); err != nil {
+			t.Fatalf("Set returned error: %v", err)
+		}
+	}
+
+	count, err := value.DeleteMatching(ctx, "a:*", MatchDryRun())
+	if err != nil {
+		t.Fatalf("DeleteMatching returned error: %v", err)
+	}
+	if count != 2 || len(store.Keys()) != 3 {
+		t.Fatalf("expected dry run to count 2 keys without deleting, got %d and %v", count, store.Keys())
+	}
+
+	updated, err := value.ExpireMatching(ctx, "a:*", time.Minute)
+	if err != nil {
+		t.Fatalf("ExpireMatching returned error: %v", err)
+	}
+	if updated != 2 {
+		t.Fatalf("expected 2 updated keys, got %d", updated)
+	}
+
+	store.Advance(time.Minute)
+	if keys := store.Keys(); len(keys) != 1 || keys[0] != "bulk:b:1" {
+		t.Fatalf("expected only bulk:b:1 to remain, got %v", keys)
+	}
+}
@@ -0,0 +1,444 @@
+package rv
+
+import (
+	"bytes"
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/redis/rueidis"
+	"github.com/redis/rueidis/rueidislock"
+)
+
+// MemoryStore is an in-memory Store for tests. It honors TTLs against a manual clock that only moves
+// forward through Advance, matches keys with Redis glob semantics and implements NX/XX conditions.
+type MemoryStore struct {
+	mu      sync.Mutex
+	now     time.Time
+	entries map[string]memoryEntry
+}
+
+type memoryEntry struct {
+	value     []byte
+	expiresAt time.Time
+}
+
+// NewMemoryStore instantiates an empty MemoryStore whose clock starts at the current time.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{now: time.Now(), entries: make(map[string]memoryEntry)}
+}
+
+// Now returns the current time of the store clock.
+func (m *MemoryStore) Now() time.Time {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.now
+}
+
+// Advance moves the store clock forward, expiring keys whose TTL elapses.
+func (m *MemoryStore) Advance(d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.now = m.now.Add(d)
+}
+
+// Keys returns the live keys in the store in sorted order.
+func (m *MemoryStore) Keys() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	keys := make([]string, 0, len(m.entries))
+	for key := range m.entries {
+		if _, ok := m.lookup(key); ok {
+			keys = append(keys, key)
+		}
+	}
+	slices.Sort(keys)
+
+	return keys
+}
+
+func (m *MemoryStore) Get(_ context.Context, key string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry, ok := m.lookup(key)
+	if !ok {
+		return nil, rueidis.Nil
+	}
+
+	return slices.Clone(entry.value), nil
+}
+
+func (m *MemoryStore) GetTTL(_ context.Context, key string) ([]byte, time.Duration, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry, ok := m.lookup(key)
+	if !ok {
+		return nil, 0, rueidis.Nil
+	}
+	if entry.expiresAt.IsZero() {
+		return slices.Clone(entry.value), -1, nil
+	}
+
+	return slices.Clone(entry.value), entry.expiresAt.Sub(m.now), nil
+}
+
+func (m *MemoryStore) MGet(_ context.Context, keys []string) (map[string][]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	values := make(map[string][]byte, len(keys))
+	for _, key := range keys {
+		if entry, ok := m.lookup(key); ok {
+			values[key] = slices.Clone(entry.value)
+		}
+	}
+
+	return values, nil
+}
+
+func (m *MemoryStore) Set(_ context.Context, key string, value []byte, args SetArgs) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	existing, exists := m.lookup(key)
+	if (args.NX && exists) || (args.XX && !exists) {
+		return rueidis.Nil
+	}
+
+	entry := memoryEntry{value: slices.Clone(value)}
+	if args.TTL > 0 {
+		entry.expiresAt = m.now.Add(args.TTL)
+	} else if args.KeepTTL && exists {
+		entry.expiresAt = existing.expiresAt
+	}
+
+	m.entries[key] = entry
+
+	return nil
+}
+
+func (m *MemoryStore) Delete(_ context.Context, keys ...string) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var deleted int64
+	for _, key := range keys {
+		if _, ok := m.lookup(key); ok {
+			delete(m.entries, key)
+			deleted++
+		}
+	}
+
+	return deleted, nil
+}
+
+func (m *MemoryStore) Unlink(ctx context.Context, keys ...string) (int64, error) {
+	return m.Delete(ctx, keys...)
+}
+
+func (m *MemoryStore) Expire(_ context.Context, ttl time.Duration, keys ...string) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var updated int64
+	for _, key := range keys {
+		entry, ok := m.lookup(key)
+		if !ok {
+			continue
+		}
+		updated++
+
+		if ttl <= 0 {
+			delete(m.entries, key)
+			continue
+		}
+
+		entry.expiresAt = m.now.Add(ttl)
+		m.entries[key] = entry
+	}
+
+	return updated, nil
+}
+
+func (m *MemoryStore) TTL(_ context.Context, key string) (time.Duration, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry, ok := m.lookup(key)
+	if !ok {
+		return 0, rueidis.Nil
+	}
+	if entry.expiresAt.IsZero() {
+		return -1, nil
+	}
+
+	return entry.expiresAt.Sub(m.now), nil
+}
+
+func (m *MemoryStore) TTLs(ctx context.Context, keys []string) (map[string]time.Duration, error) {
+	ttls := make(map[string]time.Duration, len(keys))
+	for _, key := range keys {
+		if ttl, err := m.TTL(ctx, key); err == nil {
+			ttls[key] = ttl
+		}
+	}
+
+	return ttls, nil
+}
+
+// Scan returns every matching key in a single page.
+func (m *MemoryStore) Scan(_ context.Context, _ uint64, match string, _ int64) ([]string, uint64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var keys []string
+	for key := range m.entries {
+		if _, ok := m.lookup(key); ok && matchGlob(match, key) {
+			keys = append(keys, key)
+		}
+	}
+	slices.Sort(keys)
+
+	return keys, 0, nil
+}
+
+func (m *MemoryStore) CompareAndSwap(_ context.Context, key string, old, value []byte, args SetArgs) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	existing, ok := m.lookup(key)
+	if !ok || !bytes.Equal(existing.value, old) {
+		return false, nil
+	}
+
+	if value == nil {
+		delete(m.entries, key)
+		return true, nil
+	}
+
+	entry := memoryEntry{value: slices.Clone(value)}
+	if args.TTL > 0 {
+		entry.expiresAt = m.now.Add(args.TTL)
+	} else if args.KeepTTL {
+		entry.expiresAt = existing.expiresAt
+	}
+
+	m.entries[key] = entry
+
+	return true, nil
+}
+
+// lookup returns the live entry for key, evicting it if it has expired. The caller must hold m.mu.
+func (m *MemoryStore) lookup(key string) (memoryEntry, bool) {
+	entry, ok := m.entries[key]
+	if !ok {
+		return memoryEntry{}, false
+	}
+
+	if !entry.expiresAt.IsZero() && !m.now.Before(entry.expiresAt) {
+		delete(m.entries, key)
+		return memoryEntry{}, false
+	}
+
+	return entry, true
+}
+
+// matchGlob reports whether s matches the Redis glob pattern, supporting *, ?, [...] classes with ^ negation
+// and ranges, and backslash escapes.
+func matchGlob(pattern, s string) bool {
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case '*':
+			for len(pattern) > 1 && pattern[1] == '*' {
+				pattern = pattern[1:]
+			}
+			if len(pattern) == 1 {
+				return true
+			}
+			for i := 0; i <= len(s); i++ {
+				if matchGlob(pattern[1:], s[i:]) {
+					return true
+				}
+			}
+			return false
+		case '?':
+			if len(s) == 0 {
+				return false
+			}
+			s = s[1:]
+			pattern = pattern[1:]
+		case '[':
+			if len(s) == 0 {
+				return false
+			}
+			matched, rest := matchClass(pattern[1:], s[0])
+			if !matched {
+				return false
+			}
+			s = s[1:]
+			pattern = rest
+		case '\\':
+			if len(pattern) >= 2 {
+				pattern = pattern[1:]
+			}
+			fallthrough
+		default:
+			if len(s) == 0 || pattern[0] != s[0] {
+				return false
+			}
+			s = s[1:]
+			pattern = pattern[1:]
+		}
+	}
+
+	return len(s) == 0
+}
+
+// matchClass matches c against the character class at the start of pattern (after the opening bracket)
+// and returns the pattern following the closing bracket.
+func matchClass(pattern string, c byte) (bool, string) {
+	negate := len(pattern) > 0 && pattern[0] == '^'
+	if negate {
+		pattern = pattern[1:]
+	}
+
+	matched := false
+	for len(pattern) > 0 && pattern[0] != ']' {
+		switch {
+		case pattern[0] == '\\' && len(pattern) >= 2:
+			if pattern[1] == c {
+				matched = true
+			}
+			pattern = pattern[2:]
+		case len(pattern) >= 3 && pattern[1] == '-' && pattern[2] != ']':
+			lo, hi := pattern[0], pattern[2]
+			if lo > hi {
+				lo, hi = hi, lo
+			}
+			if c >= lo && c <= hi {
+				matched = true
+			}
+			pattern = pattern[3:]
+		default:
+			if pattern[0] == c {
+				matched = true
+			}
+			pattern = pattern[1:]
+		}
+	}
+
+	if len(pattern) > 0 {
+		pattern = pattern[1:] // closing bracket
+	}
+
+	return matched != negate, pattern
+}
+
+// MemoryLocker is an in-process rueidislock.Locker for tests, providing the same mutual exclusion
+// semantics as the Redis-backed locker within a single process.
+type MemoryLocker struct {
+	mu    sync.Mutex
+	locks map[string]*memoryLock
+}
+
+type memoryLock struct {
+	released chan struct{}
+	cancel   context.CancelFunc
+}
+
+var _ rueidislock.Locker = (*MemoryLocker)(nil)
+
+// NewMemoryLocker instantiates a MemoryLocker without any held locks.
+func NewMemoryLocker() *MemoryLocker {
+	return &MemoryLocker{locks: make(map[string]*memoryLock)}
+}
+
+// WithContext acquires the lock by name, waiting until it is released or ctx is done.
+func (m *MemoryLocker) WithContext(ctx context.Context, name string) (context.Context, context.CancelFunc, error) {
+	for {
+		m.mu.Lock()
+		held, ok := m.locks[name]
+		if !ok {
+			lockCtx, cancel := m.acquire(ctx, name)
+			m.mu.Unlock()
+			return lockCtx, cancel, nil
+		}
+		m.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return ctx, func() {}, ctx.Err()
+		case <-held.released:
+		}
+	}
+}
+
+// TryWithContext acquires the lock by name without waiting. It returns rueidislock.ErrNotLocked if the lock is held.
+func (m *MemoryLocker) TryWithContext(ctx context.Context, name string) (context.Context, context.CancelFunc, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.locks[name]; ok {
+		return ctx, func() {}, rueidislock.ErrNotLocked
+	}
+
+	lockCtx, cancel := m.acquire(ctx, name)
+	return lockCtx, cancel, nil
+}
+
+// ForceWithContext takes over the lock by name, canceling the context of the current holder.
+func (m *MemoryLocker) ForceWithContext(ctx context.Context, name string) (context.Context, context.CancelFunc, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if held, ok := m.locks[name]; ok {
+		held.cancel()
+		delete(m.locks, name)
+		close(held.released)
+	}
+
+	lockCtx, cancel := m.acquire(ctx, name)
+	return lockCtx, cancel, nil
+}
+
+// Client returns nil, as MemoryLocker is not backed by Redis.
+func (m *MemoryLocker) Client() rueidis.Client {
+	return nil
+}
+
+// Close releases every held lock.
+func (m *MemoryLocker) Close() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for name, held := range m.locks {
+		held.cancel()
+		delete(m.locks, name)
+		close(held.released)
+	}
+}
+
+// acquire registers a new holder for name. The caller must hold m.mu.
+func (m *MemoryLocker) acquire(ctx context.Context, name string) (context.Context, context.CancelFunc) {
+	lockCtx, cancel := context.WithCancel(ctx)
+	lock := &memoryLock{released: make(chan struct{}), cancel: cancel}
+	m.locks[name] = lock
+
+	return lockCtx, func() {
+		cancel()
+
+		m.mu.Lock()
+		defer m.mu.Unlock()
+
+		if m.locks[name] == lock {
+			delete(m.locks, name)
+			close(lock.released)
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package rv
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/redis/rueidis"
+	"github.com/redis/rueidis/rueidislock"
+)
+
+func TestMemoryStoreHonorsTTL(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	store := NewMemoryStore()
+	value := NewValue[testPayload](nil, nil, "ttl", WithStore(store), WithDefaultExpiration(time.Minute))
+
+	if err := value.Set(ctx, "key", &testPayload{Message: "short-lived"}); err != nil {
+		t.Fatalf("Set returned error: %v", err)
+	}
+
+	store.Advance(59 * time.Second)
+	if _, err := value.Get(ctx, "key"); err != nil {
+		t.Fatalf("expected key to be alive before its TTL, got %v", err)
+	}
+
+	store.Advance(time.Second)
+	if _, err := value.Get(ctx, "key"); !errors.Is(err, rueidis.Nil) {
+		t.Fatalf("expected redis nil error after TTL, got %v", err)
+	}
+}
+
+func TestMemoryStoreKeepTTLAndConditions(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	store := NewMemoryStore()
+	value := NewValue[testPayload](nil, nil, "cond", WithStore(store))
+
+	if err := value.Set(ctx, "key", &testPayload{Message: "a"}, SetXX(true)); !errors.Is(err, rueidis.Nil) {
+		t.Fatalf("expected XX on a missing key to fail, got %v", err)
+	}
+	if err := value.Set(ctx, "key", &testPayload{Message: "a"}, SetNX(true), SetTTL(time.Minute)); err != nil {
+		t.Fatalf("expected NX on a missing key to succeed, got %v", err)
+	}
+	if err := value.Set(ctx, "key", &testPayload{Message: "b"}, SetNX(true)); !errors.Is(err, rueidis.Nil) {
+		t.Fatalf("expected NX on an existing key to fail, got %v", err)
+	}
+
+	store.Advance(30 * time.Second)
+	if err := value.Set(ctx, "key", &testPayload{Message: "c"}, SetXX(true), SetKeepTTL(true)); err != nil {
+		t.Fatalf("expected XX on an existing key to succeed, got %v", err)
+	}
+
+	ttl, err := store.TTL(ctx, "cond:key")
+	if err != nil {
+		t.Fatalf("TTL returned error: %v", err)
+	}
+	if ttl != 30*time.Second {
+		t.Fatalf("expected KEEPTTL to retain 30s, got %s", ttl)
+	}
+
+	result, err := value.Get(ctx, "key")
+	if err != nil {
+		t.Fatalf("Get returned error: %v", err)
+	}
+	if result.Message != "c" {
+		t.Fatalf("unexpected payload %+v", result)
+	}
+}
+
+func TestMemoryStoreScanMatchesGlobWithinNamespace(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	store := NewMemoryStore()
+	users := NewValue[testPayload](nil, nil, "scan", WithStore(store))
+	other := NewValue[testPayload](nil, nil, "other", WithStore(store))
+
+	for _, key := range []string{"user:1", "user:2", "admin:1"} {
+		if err := users.Set(ctx, key, &testPayload{Message: key}); err != nil {
+			t.Fatalf("Set returned error: %v", err)
+		}
+	}
+	if err := other.Set(ctx, "user:3", &testPayload{Message: "other"}); err != nil {
+		t.Fatalf("Set returned error: %v", err)
+	}
+
+	values, err := users.Scan(ctx, "user:*")
+	if err != nil {
+		t.Fatalf("Scan returned error: %v", err)
+	}
+	if len(values) != 2 || values[0].Message != "user:1" || values[1].Message != "user:2" {
+		t.Fatalf("unexpected scan result %+v", values)
+	}
+}
+
+func TestMatchGlob(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		pattern string
+		input   string
+		want    bool
+	}{
+		{"*", "", true},
+		{"ns:*", "ns:a:b", true},
+		{"ns:*", "other:a", false},
+		{"h?llo", "hello", true},
+		{"h?llo", "hllo", false},
+		{"h[ae]llo", "hallo", true},
+		{"h[^e]llo", "hello", false},
+		{"h[a-c]llo", "hbllo", true},
+		{`a\*b`, "a*b", true},
+		{`a\*b`, "axb", false},
+		{"*:end", "a:b:end", true},
+	}
+
+	for _, tc := range cases {
+		if got := matchGlob(tc.pattern, tc.input); got != tc.want {
+			t.Errorf("matchGlob(%q, %q) = %v, want %v", tc.pattern, tc.input, got, tc.want)
+		}
+	}
+}
+
+func TestMemoryLockerExcludesConcurrentHolders(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	locker := NewMemoryLocker()
+	value := NewValue[testPayload](nil, locker, "lock", WithStore(NewMemoryStore()))
+
+	err := value.WithLock(ctx, "job", func(ctx context.Context) error {
+		if _, _, err := locker.TryWithContext(ctx, "lock:job"); !errors.Is(err, rueidislock.ErrNotLocked) {
+			t.Fatalf("expected ErrNotLocked while held, got %v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("WithLock returned error: %v", err)
+	}
+
+	_, release, err := locker.TryWithContext(ctx, "lock:job")
+	if err != nil {
+		t.Fatalf("expected lock to be free after WithLock, got %v", err)
+	}
+	release()
+}
+
+func TestIdempotencyWithMemoryStore(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	store := NewIdempotency[testPayload](nil, "idem", time.Minute, time.Hour, WithStore(NewMemoryStore()))
+
+	_, token, err := store.Begin(ctx, "op")
+	if err != nil || token == "" {
+		t.Fatalf("expected a fresh claim, got token=%q err=%v", token, err)
+	}
+	if _, _, err := store.Begin(ctx, "op"); !errors.Is(err, ErrRequestInFlight) {
+		t.Fatalf("expected ErrRequestInFlight, got %v", err)
+	}
+	if err := store.Complete(ctx, "op", token, &testPayload{Message: "done"}); err != nil {
+		t.Fatalf("Complete returned error: %v", err)
+	}
+
+	result, token, err := store.Begin(ctx, "op")
+	if err != nil || token != "" || result.Message != "done" {
+		t.Fatalf("expected replayed result, got %+v token=%q err=%v", result, token, err)
+	}
+}
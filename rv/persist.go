@@ -0,0 +1,360 @@
+package rv
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/redis/rueidis"
+)
+
+// ErrPersistClosed is returned by write-behind writes to a PersistentValue after Close.
+var ErrPersistClosed = errors.New("persistent value is closed")
+
+// Backing is the source of truth behind a PersistentValue, such as a database table. Load reports a missing
+// key with an error wrapping rueidis.Nil, like Store.
+type Backing[T any] interface {
+	Load(ctx context.Context, key string) (*T, error)
+	Store(ctx context.Context, key string, value *T) error
+	Delete(ctx context.Context, key string) error
+}
+
+type persistConfig struct {
+	writeBehind bool
+	interval    time.Duration
+	maxPending  int
+	attempts    int
+	backoff     time.Duration
+	onError     func(key string, err error)
+}
+
+type PersistOption func(*persistConfig)
+
+// PersistWriteBehind acknowledges writes once they are cached and persists them to the backing store in the
+// background every interval. Writes to the same key are coalesced, so only the latest one is persisted. Once
+// maxPending keys are waiting, further writes block until a flush makes room or their context is done.
+func PersistWriteBehind(interval time.Duration, maxPending int) PersistOption {
+	return func(o *persistConfig) {
+		o.writeBehind = true
+		o.interval = interval
+		o.maxPending = maxPending
+	}
+}
+
+// PersistRetry makes up to attempts tries to persist a write, doubling backoff between tries. Write-behind
+// writes that still fail are kept pending for the next flush unless a newer write supersedes them.
+func PersistRetry(attempts int, backoff time.Duration) PersistOption {
+	return func(o *persistConfig) {
+		o.attempts = attempts
+		o.backoff = backoff
+	}
+}
+
+// PersistOnError registers a callback for write-behind writes that failed to persist, as they can no longer
+// be reported to the caller.
+func PersistOnError(fn func(key string, err error)) PersistOption {
+	return func(o *persistConfig) {
+		o.onError = fn
+	}
+}
+
+// PersistentValue keeps a Value in sync with a Backing store. Reads are served from the cache and fall back to
+// the backing store on a miss, caching the loaded value. Writes are persisted write-through by default: the
+// backing store is updated first and the cache afterward, so the cache never holds a value that was not
+// persisted. With PersistWriteBehind, writes update the cache and are persisted asynchronously.
+type PersistentValue[T any] struct {
+	value   *Value[T]
+	backing Backing[T]
+	config  persistConfig
+
+	flushMu  sync.Mutex
+	mu       sync.Mutex
+	pending  map[string]pendingWrite[T]
+	inflight map[string]pendingWrite[T]
+	flushed  chan struct{}
+	wake     chan struct{}
+	closed   bool
+	stop     chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// pendingWrite is a write-behind write; a nil value deletes the key.
+type pendingWrite[T any] struct {
+	value *T
+}
+
+// NewPersistentValue connects value to backing. With PersistWriteBehind it starts a background flusher, which
+// must be stopped with Close.
+func NewPersistentValue[T any](value *Value[T], backing Backing[T], options ...PersistOption) *PersistentValue[T] {
+	p := &PersistentValue[T]{
+		value:   value,
+		backing: backing,
+		config: persistConfig{
+			interval:   time.Second,
+			maxPending: 1000,
+			attempts:   1,
+			backoff:    100 * time.Millisecond,
+		},
+	}
+
+	for _, opt := range options {
+		opt(&p.config)
+	}
+
+	if p.config.writeBehind {
+		p.pending = make(map[string]pendingWrite[T])
+		p.flushed = make(chan struct{})
+		p.wake = make(chan struct{}, 1)
+		p.stop = make(chan struct{})
+		p.done = make(chan struct{})
+		go p.run()
+	}
+
+	return p
+}
+
+// Get returns the value of key from the cache, loading it from the backing store on a miss. With write-behind,
+// writes that are not persisted yet are visible.
+func (p *PersistentValue[T]) Get(ctx context.Context, key string) (*T, error) {
+	value, err := p.value.Get(ctx, key)
+	if err == nil || !errors.Is(err, rueidis.Nil) {
+		return value, err
+	}
+
+	if write, ok := p.unpersisted(key); ok {
+		if write.value == nil {
+			return nil, fmt.Errorf("value is pending deletion: %w", rueidis.Nil)
+		}
+		return write.value, nil
+	}
+
+	value, err = p.backing.Load(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load value: %w", err)
+	}
+
+	// The value is already loaded, so a failed cache fill only costs another load later.
+	_ = p.value.Set(ctx, key, value)
+
+	return value, nil
+}
+
+// Set stores value under key in the backing store and the cache according to the write policy. With
+// write-behind, it returns ErrPersistClosed after Close, and if the write cannot be queued, the cached value is
+// evicted so it does not outlive the failed write.
+func (p *PersistentValue[T]) Set(ctx context.Context, key string, value *T) error {
+	if p.config.writeBehind {
+		if p.isClosed() {
+			return ErrPersistClosed
+		}
+		if err := p.value.Set(ctx, key, value); err != nil {
+			return err
+		}
+
+		if err := p.enqueue(ctx, key, pendingWrite[T]{value: value}); err != nil {
+			if deleteErr := p.value.Delete(context.WithoutCancel(ctx), key); deleteErr != nil {
+				return fmt.Errorf("value is not queued, but the cache holds it: %w", errors.Join(err, deleteErr))
+			}
+			return err
+		}
+
+		return nil
+	}
+
+	if err := p.persist(ctx, key, pendingWrite[T]{value: value}); err != nil {
+		return err
+	}
+
+	if err := p.value.Set(ctx, key, value); err != nil {
+		// Evict the previous value rather than leaving it stale in front of the backing store.
+		if deleteErr := p.value.Delete(ctx, key); deleteErr != nil {
+			return fmt.Errorf("value is persisted, but the cache is stale: %w", errors.Join(err, deleteErr))
+		}
+	}
+
+	return nil
+}
+
+// Delete removes key from the backing store and the cache according to the write policy. With write-behind,
+// it returns ErrPersistClosed after Close.
+func (p *PersistentValue[T]) Delete(ctx context.Context, key string) error {
+	if p.config.writeBehind {
+		if p.isClosed() {
+			return ErrPersistClosed
+		}
+		if err := p.value.Delete(ctx, key); err != nil {
+			return err
+		}
+		return p.enqueue(ctx, key, pendingWrite[T]{})
+	}
+
+	if err := p.persist(ctx, key, pendingWrite[T]{}); err != nil {
+		return err
+	}
+
+	return p.value.Delete(ctx, key)
+}
+
+// Flush persists every pending write-behind write and returns the errors of writes that failed.
+func (p *PersistentValue[T]) Flush(ctx context.Context) error {
+	if !p.config.writeBehind {
+		return nil
+	}
+
+	p.flushMu.Lock()
+	defer p.flushMu.Unlock()
+
+	p.mu.Lock()
+	batch := p.pending
+	p.inflight, p.pending = batch, make(map[string]pendingWrite[T])
+	p.mu.Unlock()
+
+	var errs []error
+	for key, write := range batch {
+		err := p.persist(ctx, key, write)
+		if err == nil {
+			continue
+		}
+
+		errs = append(errs, err)
+		if p.config.onError != nil {
+			p.config.onError(key, err)
+		}
+
+		p.mu.Lock()
+		if _, superseded := p.pending[key]; !superseded {
+			p.pending[key] = write
+		}
+		p.mu.Unlock()
+	}
+
+	p.mu.Lock()
+	p.inflight = nil
+	close(p.flushed)
+	p.flushed = make(chan struct{})
+	p.mu.Unlock()
+
+	return errors.Join(errs...)
+}
+
+// Close stops the background flusher and persists the remaining write-behind writes. Later writes return
+// ErrPersistClosed.
+func (p *PersistentValue[T]) Close(ctx context.Context) error {
+	if !p.config.writeBehind {
+		return nil
+	}
+
+	p.mu.Lock()
+	p.closed = true
+	p.mu.Unlock()
+
+	p.stopOnce.Do(func() { close(p.stop) })
+	<-p.done
+
+	return p.Flush(ctx)
+}
+
+func (p *PersistentValue[T]) run() {
+	defer close(p.done)
+
+	ticker := time.NewTicker(p.config.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+		case <-p.wake:
+		}
+
+		// Failures stay pending and are reported through PersistOnError.
+		_ = p.Flush(context.Background())
+	}
+}
+
+// enqueue adds a write-behind write, coalescing it with a pending write of the same key and waiting for room
+// if too many keys are pending.
+func (p *PersistentValue[T]) enqueue(ctx context.Context, key string, write pendingWrite[T]) error {
+	for woken := false; ; woken = true {
+		p.mu.Lock()
+		if p.closed {
+			p.mu.Unlock()
+			return ErrPersistClosed
+		}
+		if _, ok := p.pending[key]; ok || len(p.pending) < p.config.maxPending {
+			p.pending[key] = write
+			p.mu.Unlock()
+			return nil
+		}
+		flushed := p.flushed
+		p.mu.Unlock()
+
+		// Flush early once; if writes keep failing, wait for the regular interval instead of hammering the
+		// backing store.
+		if !woken {
+			select {
+			case p.wake <- struct{}{}:
+			default:
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("write-behind queue is full: %w", ctx.Err())
+		case <-flushed:
+		}
+	}
+}
+
+func (p *PersistentValue[T]) isClosed() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.closed
+}
+
+// unpersisted returns the latest write-behind write of key that has not been persisted yet.
+func (p *PersistentValue[T]) unpersisted(key string) (pendingWrite[T], bool) {
+	if !p.config.writeBehind {
+		return pendingWrite[T]{}, false
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if write, ok := p.pending[key]; ok {
+		return write, true
+	}
+	write, ok := p.inflight[key]
+	return write, ok
+}
+
+// persist applies write to the backing store, retrying as configured by PersistRetry.
+func (p *PersistentValue[T]) persist(ctx context.Context, key string, write pendingWrite[T]) error {
+	backoff := p.config.backoff
+	for attempt := 1; ; attempt++ {
+		var err error
+		if write.value == nil {
+			err = p.backing.Delete(ctx, key)
+		} else {
+			err = p.backing.Store(ctx, key, write.value)
+		}
+		if err == nil {
+			return nil
+		}
+		if attempt >= p.config.attempts {
+			return fmt.Errorf("failed to persist key %q: %w", key, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("failed to persist key %q: %w", key, errors.Join(err, ctx.Err()))
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
@@ -0,0 +1,205 @@
+package rv
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/redis/rueidis"
+)
+
+// mapBacking is a Backing over a map that counts writes and can be made to fail.
+type mapBacking struct {
+	mu     sync.Mutex
+	values map[string]testPayload
+	writes int
+	err    error
+}
+
+func newMapBacking() *mapBacking {
+	return &mapBacking{values: make(map[string]testPayload)}
+}
+
+func (b *mapBacking) Load(_ context.Context, key string) (*testPayload, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	value, ok := b.values[key]
+	if !ok {
+		return nil, rueidis.Nil
+	}
+	return &value, nil
+}
+
+func (b *mapBacking) Store(_ context.Context, key string, value *testPayload) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.writes++
+	if b.err != nil {
+		return b.err
+	}
+	b.values[key] = *value
+	return nil
+}
+
+func (b *mapBacking) Delete(_ context.Context, key string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.writes++
+	if b.err != nil {
+		return b.err
+	}
+	delete(b.values, key)
+	return nil
+}
+
+func (b *mapBacking) setErr(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.err = err
+}
+
+func TestPersistentValueWriteThrough(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	store := NewMemoryStore()
+	backing := newMapBacking()
+	persistent := NewPersistentValue(NewValue[testPayload](nil, nil, "users", WithStore(store)), backing)
+
+	if err := persistent.Set(ctx, "1", &testPayload{Message: "alice"}); err != nil {
+		t.Fatalf("Set returned error: %v", err)
+	}
+	if backing.values["1"].Message != "alice" || len(store.Keys()) != 1 {
+		t.Fatalf("expected the value in both stores, got %v and %v", backing.values, store.Keys())
+	}
+
+	backing.setErr(errors.New("database is down"))
+	if err := persistent.Set(ctx, "1", &testPayload{Message: "bob"}); err == nil {
+		t.Fatal("expected Set to fail when the backing store fails")
+	}
+	backing.setErr(nil)
+
+	got, err := persistent.Get(ctx, "1")
+	if err != nil || got.Message != "alice" {
+		t.Fatalf("expected the cache to keep the persisted value, got %v, %v", got, err)
+	}
+
+	backing.values["2"] = testPayload{Message: "carol"}
+	got, err = persistent.Get(ctx, "2")
+	if err != nil || got.Message != "carol" {
+		t.Fatalf("expected a load from the backing store, got %v, %v", got, err)
+	}
+	if keys := store.Keys(); len(keys) != 2 {
+		t.Fatalf("expected the loaded value to be cached, got %v", keys)
+	}
+
+	if err := persistent.Delete(ctx, "1"); err != nil {
+		t.Fatalf("Delete returned error: %v", err)
+	}
+	if _, err := persistent.Get(ctx, "1"); !errors.Is(err, rueidis.Nil) {
+		t.Fatalf("expected a miss after Delete, got %v", err)
+	}
+}
+
+func TestPersistentValueWriteBehindCoalescesAndRetries(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	backing := newMapBacking()
+
+	var (
+		mu       sync.Mutex
+		reported []string
+	)
+	persistent := NewPersistentValue(NewValue[testPayload](nil, nil, "users", WithStore(NewMemoryStore())), backing,
+		PersistWriteBehind(time.Hour, 10),
+		PersistRetry(2, time.Millisecond),
+		PersistOnError(func(key string, err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			reported = append(reported, key)
+		}),
+	)
+	defer persistent.Close(ctx)
+
+	for i := range 3 {
+		if err := persistent.Set(ctx, "1", &testPayload{Message: fmt.Sprint("v", i)}); err != nil {
+			t.Fatalf("Set returned error: %v", err)
+		}
+	}
+	if backing.writes != 0 {
+		t.Fatalf("expected writes to be deferred, got %d", backing.writes)
+	}
+
+	backing.setErr(errors.New("database is down"))
+	if err := persistent.Flush(ctx); err == nil {
+		t.Fatal("expected Flush to report the failed write")
+	}
+	if backing.writes != 2 || len(reported) != 1 {
+		t.Fatalf("expected 2 attempts and 1 report, got %d and %v", backing.writes, reported)
+	}
+
+	backing.setErr(nil)
+	if err := persistent.Flush(ctx); err != nil {
+		t.Fatalf("Flush returned error: %v", err)
+	}
+	if backing.writes != 3 || backing.values["1"].Message != "v2" {
+		t.Fatalf("expected only the latest write to be persisted, got %d writes and %v", backing.writes, backing.values)
+	}
+}
+
+func TestPersistentValueWriteBehindBackpressure(t *testing.T) {
+	t.Parallel()
+
+	backing := newMapBacking()
+	backing.setErr(errors.New("database is down"))
+
+	store := NewMemoryStore()
+	persistent := NewPersistentValue(NewValue[testPayload](nil, nil, "users", WithStore(store)), backing,
+		PersistWriteBehind(time.Hour, 1),
+	)
+
+	if err := persistent.Set(context.Background(), "1", &testPayload{Message: "queued"}); err != nil {
+		t.Fatalf("Set returned error: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	if err := persistent.Set(ctx, "2", &testPayload{Message: "blocked"}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected Set to block while the queue is full, got %v", err)
+	}
+	if _, err := store.Get(context.Background(), "users:2"); !errors.Is(err, rueidis.Nil) {
+		t.Fatalf("expected the unqueued write to be evicted from the cache, got %v", err)
+	}
+
+	got, err := persistent.Get(context.Background(), "1")
+	if err != nil || got.Message != "queued" {
+		t.Fatalf("expected the pending write to be readable, got %v, %v", got, err)
+	}
+
+	backing.setErr(nil)
+	if err := persistent.Close(context.Background()); err != nil {
+		t.Fatalf("Close returned error: %v", err)
+	}
+	if backing.values["1"].Message != "queued" {
+		t.Fatalf("expected Close to flush pending writes, got %v", backing.values)
+	}
+
+	if err := persistent.Set(context.Background(), "3", &testPayload{Message: "late"}); !errors.Is(err, ErrPersistClosed) {
+		t.Fatalf("expected ErrPersistClosed after Close, got %v", err)
+	}
+	if err := persistent.Delete(context.Background(), "1"); !errors.Is(err, ErrPersistClosed) {
+		t.Fatalf("expected ErrPersistClosed after Close, got %v", err)
+	}
+	if _, err := store.Get(context.Background(), "users:3"); !errors.Is(err, rueidis.Nil) {
+		t.Fatalf("expected the rejected write not to be cached, got %v", err)
+	}
+}
@@ -0,0 +1,472 @@
+package rv
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/redis/rueidis"
+)
+
+var (
+	// ErrJobLost is returned when a job was claimed again after its visibility timeout, or was canceled,
+	// before it was acknowledged.
+	ErrJobLost = errors.New("job lost")
+	// ErrJobExhausted is returned by Job.Retry when the job has used up its attempts and was dead-lettered.
+	ErrJobExhausted = errors.New("job exhausted its attempts")
+)
+
+// queueClaimScript claims up to ARGV[1] due jobs by pushing their due time out by the visibility timeout
+// ARGV[2], and returns the id, payload and attempt of each. Jobs that already had ARGV[3] attempts, because
+// their workers crashed before acknowledging or retrying them, are moved to the dead letter set instead, and
+// leftovers of canceled jobs are dropped.
+// Redis' own clock is used so workers never disagree.
+var queueClaimScript = rueidis.NewLuaScript(`
+local t = redis.call('TIME')
+local now = t[1] * 1000 + math.floor(t[2] / 1000)
+local ids = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', now, 'LIMIT', 0, ARGV[1])
+local result = {}
+for _, id in ipairs(ids) do
+	local attempt = redis.call('HINCRBY', KEYS[3], id, 1)
+	local payload = redis.call('HGET', KEYS[2], id)
+	if not payload then
+		redis.call('ZREM', KEYS[1], id)
+		redis.call('HDEL', KEYS[3], id)
+	elseif attempt > tonumber(ARGV[3]) then
+		redis.call('HINCRBY', KEYS[3], id, -1)
+		redis.call('ZREM', KEYS[1], id)
+		redis.call('ZADD', KEYS[4], now, id)
+	else
+		redis.call('ZADD', KEYS[1], now + tonumber(ARGV[2]), id)
+		result[#result + 1] = id
+		result[#result + 1] = payload
+		result[#result + 1] = attempt
+	end
+end
+return result
+`)
+
+// queueAckScript removes a job, provided the caller still holds its current attempt.
+var queueAckScript = rueidis.NewLuaScript(`
+if redis.call('HGET', KEYS[3], ARGV[1]) ~= ARGV[2] then
+	return 0
+end
+redis.call('ZREM', KEYS[1], ARGV[1])
+redis.call('HDEL', KEYS[2], ARGV[1])
+redis.call('HDEL', KEYS[3], ARGV[1])
+return 1
+`)
+
+// queueRetryScript reschedules a job after ARGV[3] milliseconds, or moves it to the dead letter set once it
+// reached ARGV[4] attempts, provided the caller still holds its current attempt.
+var queueRetryScript = rueidis.NewLuaScript(`
+if redis.call('HGET', KEYS[2], ARGV[1]) ~= ARGV[2] then
+	return 0
+end
+local t = redis.call('TIME')
+local now = t[1] * 1000 + math.floor(t[2] / 1000)
+if tonumber(ARGV[2]) >= tonumber(ARGV[4]) then
+	redis.call('ZREM', KEYS[1], ARGV[1])
+	redis.call('ZADD', KEYS[3], now, ARGV[1])
+	return 2
+end
+redis.call('ZADD', KEYS[1], now + tonumber(ARGV[3]), ARGV[1])
+return 1
+`)
+
+// queueExtendScript makes a claimed job invisible for ARGV[3] milliseconds from now, provided the caller still
+// holds its current attempt.
+var queueExtendScript = rueidis.NewLuaScript(`
+if redis.call('HGET', KEYS[2], ARGV[1]) ~= ARGV[2] or not redis.call('ZSCORE', KEYS[1], ARGV[1]) then
+	return 0
+end
+local t = redis.call('TIME')
+local now = t[1] * 1000 + math.floor(t[2] / 1000)
+redis.call('ZADD', KEYS[1], now + tonumber(ARGV[3]), ARGV[1])
+return 1
+`)
+
+type queueConfig struct {
+	maxAttempts int
+	minBackoff  time.Duration
+	maxBackoff  time.Duration
+	poll        time.Duration
+	onDecode    func(id string, err error)
+}
+
+type QueueOption func(*queueConfig)
+
+// QueueMaxAttempts sets how many times a job is attempted before it is dead-lettered. The default is 5.
+func QueueMaxAttempts(attempts int) QueueOption {
+	return func(o *queueConfig) {
+		o.maxAttempts = attempts
+	}
+}
+
+// QueueBackoff sets the delay before a failed job is retried, starting at minBackoff and doubling with every
+// attempt up to maxBackoff. The default is 1 second up to 5 minutes.
+func QueueBackoff(minBackoff, maxBackoff time.Duration) QueueOption {
+	return func(o *queueConfig) {
+		o.minBackoff = minBackoff
+		o.maxBackoff = maxBackoff
+	}
+}
+
+// QueuePollInterval sets how often Run looks for due jobs while it has idle workers. The default is 1 second.
+func QueuePollInterval(interval time.Duration) QueueOption {
+	return func(o *queueConfig) {
+		o.poll = interval
+	}
+}
+
+// QueueOnDecodeError registers a callback invoked by Claim with the ID of every claimed job whose payload cannot
+// be decoded into T, e.g. after an incompatible change of T. Such jobs are dead-lettered right away and the
+// other jobs of the batch are still returned.
+func QueueOnDecodeError(fn func(id string, err error)) QueueOption {
+	return func(o *queueConfig) {
+		o.onDecode = fn
+	}
+}
+
+// DelayQueue schedules typed jobs to run at a future time. Jobs are kept in a sorted set keyed by due time
+// and delivered at least once: a claimed job becomes due again once its visibility timeout elapses unless it
+// is acknowledged, and failed jobs are retried with exponential backoff until they are dead-lettered.
+//
+// Payloads are encoded with CBOR. All keys share the {key} hash tag, so the queue works on Redis Cluster.
+type DelayQueue[T any] struct {
+	client     rueidis.Client
+	key        string
+	visibility time.Duration
+
+	config queueConfig
+}
+
+// NewDelayQueue instantiates a DelayQueue named by key. A claimed job is invisible to other workers for
+// visibility, which must exceed the time needed to process it unless the handler calls Job.Extend.
+func NewDelayQueue[T any](client rueidis.Client, key string, visibility time.Duration, options ...QueueOption) *DelayQueue[T] {
+	q := &DelayQueue[T]{
+		client:     client,
+		key:        key,
+		visibility: visibility,
+		config: queueConfig{
+			maxAttempts: 5,
+			minBackoff:  time.Second,
+			maxBackoff:  5 * time.Minute,
+			poll:        time.Second,
+		},
+	}
+
+	for _, opt := range options {
+		opt(&q.config)
+	}
+
+	return q
+}
+
+// Job is a claimed job. It must be acknowledged with Ack once processed, or handed back with Retry.
+type Job[T any] struct {
+	// ID identifies the job within its queue.
+	ID string
+	// Payload is the scheduled job.
+	Payload *T
+	// Attempt counts the deliveries of the job, starting at 1.
+	Attempt int64
+
+	queue *DelayQueue[T]
+}
+
+// Schedule adds a job that becomes due at the given time and returns its ID. Due times are compared against
+// the clock of the Redis server.
+func (q *DelayQueue[T]) Schedule(ctx context.Context, at time.Time, payload *T) (string, error) {
+	encoded, err := cbor.Marshal(payload)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode job: %w", err)
+	}
+
+	due, jobs, _, _ := q.keys()
+	id := rand.Text()
+
+	// The payload is stored first, so the job is never claimed without it.
+	for _, resp := range q.client.DoMulti(ctx,
+		q.client.B().Hset().Key(jobs).FieldValue().FieldValue(id, rueidis.BinaryString(encoded)).Build(),
+		q.client.B().Zadd().Key(due).ScoreMember().ScoreMember(float64(at.UnixMilli()), id).Build(),
+	) {
+		if err := resp.Error(); err != nil {
+			return "", fmt.Errorf("failed to schedule job: %w", err)
+		}
+	}
+
+	return id, nil
+}
+
+// ScheduleAfter adds a job that becomes due after delay and returns its ID.
+func (q *DelayQueue[T]) ScheduleAfter(ctx context.Context, delay time.Duration, payload *T) (string, error) {
+	return q.Schedule(ctx, time.Now().Add(delay), payload)
+}
+
+// Cancel removes a job, whether it is scheduled, claimed or dead-lettered.
+func (q *DelayQueue[T]) Cancel(ctx context.Context, id string) error {
+	due, jobs, attempts, dead := q.keys()
+
+	for _, resp := range q.client.DoMulti(ctx,
+		q.client.B().Zrem().Key(due).Member(id).Build(),
+		q.client.B().Zrem().Key(dead).Member(id).Build(),
+		q.client.B().Hdel().Key(jobs).Field(id).Build(),
+		q.client.B().Hdel().Key(attempts).Field(id).Build(),
+	) {
+		if err := resp.Error(); err != nil {
+			return fmt.Errorf("failed to cancel job: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// Claim atomically claims up to limit due jobs, hiding them from other workers for the visibility timeout.
+// Due jobs that were already delivered as often as QueueMaxAttempts allows are dead-lettered instead, so a job
+// that keeps crashing its worker is not redelivered forever, and so are claimed jobs whose payload cannot be
+// decoded, see QueueOnDecodeError. Claim may therefore return fewer jobs than limit while more are due.
+func (q *DelayQueue[T]) Claim(ctx context.Context, limit int) ([]*Job[T], error) {
+	due, jobs, attempts, dead := q.keys()
+
+	resp, err := queueClaimScript.Exec(ctx, q.client, []string{due, jobs, attempts, dead}, []string{
+		strconv.Itoa(limit),
+		strconv.FormatInt(q.visibility.Milliseconds(), 10),
+		strconv.Itoa(q.config.maxAttempts),
+	}).ToArray()
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim jobs: %w", err)
+	}
+
+	claimed := make([]*Job[T], 0, len(resp)/3)
+	for i := 0; i+2 < len(resp); i += 3 {
+		id, err := resp[i].ToString()
+		if err != nil {
+			return nil, fmt.Errorf("failed to claim jobs: %w", err)
+		}
+		data, err := resp[i+1].AsBytes()
+		if err != nil {
+			return nil, fmt.Errorf("failed to claim jobs: %w", err)
+		}
+		attempt, err := resp[i+2].AsInt64()
+		if err != nil {
+			return nil, fmt.Errorf("failed to claim jobs: %w", err)
+		}
+
+		job := &Job[T]{ID: id, Attempt: attempt, queue: q}
+
+		var payload T
+		if err := cbor.Unmarshal(data, &payload); err != nil {
+			// Retrying cannot help, and the rest of the batch is already claimed. If dead-lettering fails, the
+			// job is claimed again once its visibility timeout elapses.
+			_ = job.deadLetter(ctx)
+			if q.config.onDecode != nil {
+				q.config.onDecode(id, fmt.Errorf("failed to decode job %q: %w", id, err))
+			}
+			continue
+		}
+
+		job.Payload = &payload
+		claimed = append(claimed, job)
+	}
+
+	return claimed, nil
+}
+
+// Run claims due jobs and processes them with handler on up to workers goroutines until ctx is done. Jobs for
+// which handler returns nil are acknowledged, the others are retried. Claim failures are retried on the next
+// poll; Redis being briefly unavailable must not stop the workers. Run waits for running handlers before it
+// returns ctx.Err().
+func (q *DelayQueue[T]) Run(ctx context.Context, workers int, handler func(ctx context.Context, job *Job[T]) error) error {
+	slots := make(chan struct{}, workers)
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	for {
+		if idle := workers - len(slots); idle > 0 {
+			jobs, err := q.Claim(ctx, idle)
+			if err == nil {
+				for _, job := range jobs {
+					slots <- struct{}{}
+					wg.Go(func() {
+						defer func() { <-slots }()
+						q.process(ctx, job, handler)
+					})
+				}
+
+				// A full batch suggests more jobs are due, so claim again right away.
+				if len(jobs) == idle {
+					continue
+				}
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(q.config.poll):
+		}
+	}
+}
+
+func (q *DelayQueue[T]) process(ctx context.Context, job *Job[T], handler func(ctx context.Context, job *Job[T]) error) {
+	// A job that fails to be acknowledged or retried becomes due again after its visibility timeout.
+	if err := handler(ctx, job); err != nil {
+		_ = job.Retry(ctx)
+		return
+	}
+	_ = job.Ack(ctx)
+}
+
+// Ack removes the processed job from the queue. It returns ErrJobLost if the job was claimed again or
+// canceled meanwhile.
+func (j *Job[T]) Ack(ctx context.Context) error {
+	due, jobs, attempts, _ := j.queue.keys()
+
+	ok, err := queueAckScript.Exec(ctx, j.queue.client, []string{due, jobs, attempts}, []string{
+		j.ID,
+		strconv.FormatInt(j.Attempt, 10),
+	}).AsInt64()
+	if err != nil {
+		return fmt.Errorf("failed to acknowledge job: %w", err)
+	}
+	if ok == 0 {
+		return ErrJobLost
+	}
+
+	return nil
+}
+
+// Extend hides the job from other workers for another timeout, counted from now, so a long-running handler keeps
+// its claim. It returns ErrJobLost if the job was claimed again or canceled meanwhile.
+func (j *Job[T]) Extend(ctx context.Context, timeout time.Duration) error {
+	due, _, attempts, _ := j.queue.keys()
+
+	ok, err := queueExtendScript.Exec(ctx, j.queue.client, []string{due, attempts}, []string{
+		j.ID,
+		strconv.FormatInt(j.Attempt, 10),
+		strconv.FormatInt(timeout.Milliseconds(), 10),
+	}).AsInt64()
+	if err != nil {
+		return fmt.Errorf("failed to extend job: %w", err)
+	}
+	if ok == 0 {
+		return ErrJobLost
+	}
+
+	return nil
+}
+
+// Retry hands the job back to be attempted again after the backoff. Once the job has used up its attempts it
+// is dead-lettered instead and ErrJobExhausted is returned. It returns ErrJobLost if the job was claimed again
+// or canceled meanwhile.
+func (j *Job[T]) Retry(ctx context.Context) error {
+	due, _, attempts, dead := j.queue.keys()
+
+	result, err := queueRetryScript.Exec(ctx, j.queue.client, []string{due, attempts, dead}, []string{
+		j.ID,
+		strconv.FormatInt(j.Attempt, 10),
+		strconv.FormatInt(j.queue.backoff(j.Attempt).Milliseconds(), 10),
+		strconv.Itoa(j.queue.config.maxAttempts),
+	}).AsInt64()
+	if err != nil {
+		return fmt.Errorf("failed to retry job: %w", err)
+	}
+
+	switch result {
+	case 0:
+		return ErrJobLost
+	case 2:
+		return ErrJobExhausted
+	}
+
+	return nil
+}
+
+// deadLetter moves the job to the dead letter set regardless of its remaining attempts, by retrying it with the
+// current attempt as the limit.
+func (j *Job[T]) deadLetter(ctx context.Context) error {
+	due, _, attempts, dead := j.queue.keys()
+
+	err := queueRetryScript.Exec(ctx, j.queue.client, []string{due, attempts, dead}, []string{
+		j.ID,
+		strconv.FormatInt(j.Attempt, 10),
+		"0",
+		strconv.FormatInt(j.Attempt, 10),
+	}).Error()
+	if err != nil {
+		return fmt.Errorf("failed to dead-letter job: %w", err)
+	}
+
+	return nil
+}
+
+// DeadLetters returns up to limit dead-lettered jobs, oldest first, or all of them if limit is 0. They stay
+// dead-lettered until canceled. Jobs whose payload cannot be decoded are returned with a nil Payload, so they can
+// still be inspected by ID and canceled.
+func (q *DelayQueue[T]) DeadLetters(ctx context.Context, limit int64) ([]*Job[T], error) {
+	_, jobs, attempts, dead := q.keys()
+
+	ids, err := q.client.Do(ctx, q.client.B().Zrange().Key(dead).Min("0").Max(strconv.FormatInt(limit-1, 10)).Build()).AsStrSlice()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list dead letters: %w", err)
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	resps := q.client.DoMulti(ctx,
+		q.client.B().Hmget().Key(jobs).Field(ids...).Build(),
+		q.client.B().Hmget().Key(attempts).Field(ids...).Build(),
+	)
+	payloads, err := resps[0].ToArray()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load dead letters: %w", err)
+	}
+	counts, err := resps[1].ToArray()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load dead letters: %w", err)
+	}
+
+	letters := make([]*Job[T], 0, len(ids))
+	for i, id := range ids {
+		data, err := payloads[i].AsBytes()
+		if err != nil {
+			continue // canceled meanwhile
+		}
+
+		attempt, _ := counts[i].AsInt64()
+		letter := &Job[T]{ID: id, Attempt: attempt, queue: q}
+
+		var payload T
+		if err := cbor.Unmarshal(data, &payload); err == nil {
+			letter.Payload = &payload
+		}
+
+		letters = append(letters, letter)
+	}
+
+	return letters, nil
+}
+
+// backoff returns the delay before the next attempt of a job that failed its attempt-th delivery.
+func (q *DelayQueue[T]) backoff(attempt int64) time.Duration {
+	delay := q.config.minBackoff
+	for i := int64(1); i < attempt && delay < q.config.maxBackoff; i++ {
+		delay *= 2
+	}
+
+	return min(delay, q.config.maxBackoff)
+}
+
+// keys returns the due set, payload hash, attempt hash and dead letter set, hash-tagged to share a cluster slot.
+func (q *DelayQueue[T]) keys() (due, jobs, attempts, dead string) {
+	tag := "{" + q.key + "}"
+	return tag + ":due", tag + ":jobs", tag + ":attempts", tag + ":dead"
+}
@@ -0,0 +1,239 @@
+package rv
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/redis/rueidis"
+	rueidismock "github.com/redis/rueidis/mock"
+	"go.uber.org/mock/gomock"
+)
+
+func TestDelayQueueScheduleStoresPayloadBeforeDueTime(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	client := rueidismock.NewClient(ctrl)
+	queue := NewDelayQueue[testPayload](client, "reminders", time.Minute)
+	at := time.UnixMilli(1760000000000)
+
+	client.EXPECT().
+		DoMulti(ctx,
+			rueidismock.MatchFn(func(tokens []string) bool {
+				return len(tokens) == 4 && tokens[0] == "HSET" && tokens[1] == "{reminders}:jobs"
+			}, "HSET payload"),
+			rueidismock.MatchFn(func(tokens []string) bool {
+				return len(tokens) == 4 && tokens[0] == "ZADD" && tokens[1] == "{reminders}:due" && tokens[2] == "1760000000000"
+			}, "ZADD due time"),
+		).
+		Return([]rueidis.RedisResult{
+			rueidismock.Result(rueidismock.RedisInt64(1)),
+			rueidismock.Result(rueidismock.RedisInt64(1)),
+		})
+
+	id, err := queue.Schedule(ctx, at, &testPayload{Message: "ping"})
+	if err != nil {
+		t.Fatalf("Schedule returned error: %v", err)
+	}
+	if id == "" {
+		t.Fatal("expected a job ID")
+	}
+}
+
+func TestDelayQueueRunAcknowledgesAndRetries(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	client := rueidismock.NewClient(ctrl)
+	queue := NewDelayQueue[testPayload](client, "reminders", time.Minute,
+		QueuePollInterval(time.Millisecond), QueueBackoff(time.Second, time.Minute))
+
+	encode := func(message string) rueidis.RedisMessage {
+		data, err := cbor.Marshal(testPayload{Message: message})
+		if err != nil {
+			t.Fatalf("failed to encode payload: %v", err)
+		}
+		return rueidismock.RedisBlobString(string(data))
+	}
+
+	// Claims pass the visibility timeout and the attempt limit, where acknowledgements pass the attempt.
+	claim := rueidismock.MatchFn(func(tokens []string) bool {
+		return len(tokens) == 10 && tokens[0] == "EVALSHA" && tokens[3] == "{reminders}:due" &&
+			tokens[6] == "{reminders}:dead" && tokens[8] == "60000" && tokens[9] == "5"
+	}, "EVALSHA claim")
+	gomock.InOrder(
+		client.EXPECT().
+			Do(gomock.Any(), claim).
+			Return(rueidismock.Result(rueidismock.RedisArray(
+				rueidismock.RedisString("ok"), encode("ok"), rueidismock.RedisInt64(1),
+				rueidismock.RedisString("fail"), encode("fail"), rueidismock.RedisInt64(3),
+			))),
+		client.EXPECT().
+			Do(gomock.Any(), claim).
+			Return(rueidismock.Result(rueidismock.RedisArray())).
+			AnyTimes(),
+	)
+
+	acked := make(chan struct{})
+	client.EXPECT().
+		Do(gomock.Any(), rueidismock.MatchFn(func(tokens []string) bool {
+			return len(tokens) == 8 && tokens[0] == "EVALSHA" && tokens[6] == "ok" && tokens[7] == "1"
+		}, "EVALSHA ack")).
+		DoAndReturn(func(context.Context, rueidis.Completed) rueidis.RedisResult {
+			close(acked)
+			return rueidismock.Result(rueidismock.RedisInt64(1))
+		})
+
+	retried := make(chan struct{})
+	client.EXPECT().
+		Do(gomock.Any(), rueidismock.MatchFn(func(tokens []string) bool {
+			// The third attempt backs off for 4 seconds.
+			return len(tokens) == 10 && tokens[0] == "EVALSHA" && tokens[3] == "{reminders}:due" &&
+				tokens[6] == "fail" && tokens[7] == "3" && tokens[8] == "4000" && tokens[9] == "5"
+		}, "EVALSHA retry")).
+		DoAndReturn(func(context.Context, rueidis.Completed) rueidis.RedisResult {
+			close(retried)
+			return rueidismock.Result(rueidismock.RedisInt64(1))
+		})
+
+	done := make(chan error, 1)
+	go func() {
+		done <- queue.Run(ctx, 2, func(_ context.Context, job *Job[testPayload]) error {
+			if job.Payload.Message == "fail" {
+				return errors.New("downstream unavailable")
+			}
+			return nil
+		})
+	}()
+
+	for _, ch := range []chan struct{}{acked, retried} {
+		select {
+		case <-ch:
+		case <-time.After(time.Second):
+			t.Fatal("timed out waiting for the job outcome")
+		}
+	}
+
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected Run to return context.Canceled, got %v", err)
+	}
+}
+
+func TestJobRetryReportsExhaustion(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	client := rueidismock.NewClient(ctrl)
+	queue := NewDelayQueue[testPayload](client, "reminders", time.Minute, QueueMaxAttempts(1))
+	job := &Job[testPayload]{ID: "job", Attempt: 1, queue: queue}
+
+	client.EXPECT().
+		Do(ctx, matchEvalshaCommand("{reminders}:due", "{reminders}:attempts", "{reminders}:dead")).
+		Return(rueidismock.Result(rueidismock.RedisInt64(2)))
+
+	if err := job.Retry(ctx); !errors.Is(err, ErrJobExhausted) {
+		t.Fatalf("expected ErrJobExhausted, got %v", err)
+	}
+}
+
+func TestDelayQueueBackoff(t *testing.T) {
+	t.Parallel()
+
+	queue := NewDelayQueue[testPayload](nil, "reminders", time.Minute, QueueBackoff(time.Second, 10*time.Second))
+
+	for attempt, want := range map[int64]time.Duration{1: time.Second, 2: 2 * time.Second, 4: 8 * time.Second, 10: 10 * time.Second} {
+		if got := queue.backoff(attempt); got != want {
+			t.Errorf("backoff(%d) = %v, want %v", attempt, got, want)
+		}
+	}
+}
+
+func TestJobExtendReportsLostJob(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	client := rueidismock.NewClient(ctrl)
+	queue := NewDelayQueue[testPayload](client, "reminders", time.Minute)
+	job := &Job[testPayload]{ID: "job", Attempt: 2, queue: queue}
+
+	extend := rueidismock.MatchFn(func(tokens []string) bool {
+		return matchEvalshaCommand("{reminders}:due", "{reminders}:attempts").Matches(tokens) &&
+			tokens[5] == "job" && tokens[6] == "2" && tokens[7] == "300000"
+	}, "EVALSHA extend")
+	gomock.InOrder(
+		client.EXPECT().Do(ctx, extend).Return(rueidismock.Result(rueidismock.RedisInt64(1))),
+		client.EXPECT().Do(ctx, extend).Return(rueidismock.Result(rueidismock.RedisInt64(0))),
+	)
+
+	if err := job.Extend(ctx, 5*time.Minute); err != nil {
+		t.Fatalf("Extend returned error: %v", err)
+	}
+	if err := job.Extend(ctx, 5*time.Minute); !errors.Is(err, ErrJobLost) {
+		t.Fatalf("expected ErrJobLost, got %v", err)
+	}
+}
+
+func TestDelayQueueClaimDeadLettersUndecodableJob(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	client := rueidismock.NewClient(ctrl)
+
+	var reported []string
+	queue := NewDelayQueue[testPayload](client, "reminders", time.Minute, QueueOnDecodeError(func(id string, err error) {
+		reported = append(reported, id)
+	}))
+
+	good, err := cbor.Marshal(testPayload{Message: "good"})
+	if err != nil {
+		t.Fatalf("failed to encode payload: %v", err)
+	}
+
+	gomock.InOrder(
+		client.EXPECT().
+			Do(ctx, matchEvalshaCommand("{reminders}:due", "{reminders}:jobs", "{reminders}:attempts", "{reminders}:dead")).
+			Return(rueidismock.Result(rueidismock.RedisArray(
+				rueidismock.RedisString("bad"), rueidismock.RedisBlobString("\xff"), rueidismock.RedisInt64(2),
+				rueidismock.RedisString("good"), rueidismock.RedisBlobString(string(good)), rueidismock.RedisInt64(1),
+			))),
+		client.EXPECT().
+			Do(ctx, rueidismock.MatchFn(func(tokens []string) bool {
+				// The retry script dead-letters a job whose attempt reached the limit passed last.
+				return matchEvalshaCommand("{reminders}:due", "{reminders}:attempts", "{reminders}:dead").Matches(tokens) &&
+					tokens[len(tokens)-4] == "bad" && tokens[len(tokens)-3] == "2" && tokens[len(tokens)-1] == "2"
+			}, "EVALSHA dead-letter")).
+			Return(rueidismock.Result(rueidismock.RedisInt64(2))),
+	)
+
+	jobs, err := queue.Claim(ctx, 2)
+	if err != nil {
+		t.Fatalf("Claim returned error: %v", err)
+	}
+	if len(jobs) != 1 || jobs[0].ID != "good" || jobs[0].Payload.Message != "good" {
+		t.Fatalf("expected the decodable job to be claimed, got %+v", jobs)
+	}
+	if len(reported) != 1 || reported[0] != "bad" {
+		t.Fatalf("expected the undecodable job to be reported, got %v", reported)
+	}
+}
@@ -0,0 +1,155 @@
+package rv
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/redis/rueidis"
+)
+
+// envelopeTag is the CBOR tag wrapping payloads that carry metadata such as a schema version.
+// 0x7276 spells "rv" and encodes as the fixed three-byte prefix envelopePrefix.
+const envelopeTag = 0x7276
+
+var envelopePrefix = []byte{0xd9, 0x72, 0x76}
+
+// envelope wraps an encoded T together with its metadata. Payloads written without an envelope
+// are treated as schema version 0.
+type envelope struct {
+	Payload cbor.RawMessage `cbor:"0,keyasint"`
+	Version uint64          `cbor:"1,keyasint,omitempty"`
+	Delta   time.Duration   `cbor:"2,keyasint,omitempty"` // recomputation time, see WithEarlyExpiration
+}
+
+// schemaUpgrade decodes a payload of an older schema version and converts it into target, the current type.
+type schemaUpgrade struct {
+	target reflect.Type
+	decode func(data []byte) (any, error)
+}
+
+// WithSchemaVersion declares the schema version of T. Set stores the version alongside the payload, and Get
+// upgrades entries of older versions with the functions registered through Upgrade. Entries of any other
+// version, including ones written before versioning was enabled (version 0) without an upgrade, are discarded
+// and reported as missing, as if they had expired.
+func WithSchemaVersion(version uint64) Option {
+	return func(r *valueConfig) {
+		r.schemaVersion = &version
+	}
+}
+
+// Upgrade registers a conversion for entries stored with schema version from. The entry is decoded into Old,
+// which may be the previous struct or a map[string]any for raw CBOR maps, and fn converts it into the current
+// type New, which must match the T of the Value; NewValue panics otherwise. Every upgrade converts directly into
+// the current version.
+func Upgrade[Old, New any](from uint64, fn func(old *Old) (*New, error)) Option {
+	return func(r *valueConfig) {
+		if r.upgrades == nil {
+			r.upgrades = make(map[uint64]schemaUpgrade)
+		}
+
+		r.upgrades[from] = schemaUpgrade{
+			target: reflect.TypeFor[New](),
+			decode: func(data []byte) (any, error) {
+				var old Old
+				if err := cbor.Unmarshal(data, &old); err != nil {
+					return nil, fmt.Errorf("failed to decode schema version %d: %w", from, err)
+				}
+
+				return fn(&old)
+			},
+		}
+	}
+}
+
+// checkUpgrades verifies that every registered upgrade converts into T.
+func checkUpgrades[T any](upgrades map[uint64]schemaUpgrade) error {
+	for from, upgrade := range upgrades {
+		if upgrade.target != reflect.TypeFor[T]() {
+			return fmt.Errorf("upgrade from schema version %d returns %v, expected %v", from, upgrade.target, reflect.TypeFor[T]())
+		}
+	}
+
+	return nil
+}
+
+// encodeValue transforms the generic type into the CBOR payload, wrapped in an envelope if versioned.
+func (r *Value[T]) encodeValue(value *T) ([]byte, error) {
+	return r.encodeEntry(value, 0)
+}
+
+// encodeEntry is encodeValue recording the recomputation delta, which also requires an envelope.
+func (r *Value[T]) encodeEntry(value *T, delta time.Duration) ([]byte, error) {
+	encoded, err := cbor.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode value: %w", err)
+	}
+
+	if r.config.schemaVersion == nil && delta <= 0 {
+		return encoded, nil
+	}
+
+	wrapped := envelope{Payload: encoded, Delta: max(delta, 0)}
+	if r.config.schemaVersion != nil {
+		wrapped.Version = *r.config.schemaVersion
+	}
+
+	encoded, err = cbor.Marshal(cbor.Tag{Number: envelopeTag, Content: wrapped})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode value: %w", err)
+	}
+
+	return encoded, nil
+}
+
+// decodeValue transforms the CBOR payload into the generic type, upgrading older schema versions.
+// Payloads of versions that cannot be upgraded are reported with an error wrapping rueidis.Nil.
+func (r *Value[T]) decodeValue(data []byte) (*T, error) {
+	value, _, err := r.decodeEntry(data)
+	return value, err
+}
+
+// decodeEntry is decodeValue also returning the recorded recomputation delta.
+func (r *Value[T]) decodeEntry(data []byte) (*T, time.Duration, error) {
+	var (
+		version uint64
+		delta   time.Duration
+		payload = data
+	)
+
+	if bytes.HasPrefix(data, envelopePrefix) {
+		var wrapped envelope
+		if err := cbor.Unmarshal(data[len(envelopePrefix):], &wrapped); err != nil {
+			return nil, 0, fmt.Errorf("failed to decode value envelope: %w", err)
+		}
+		version, delta, payload = wrapped.Version, wrapped.Delta, wrapped.Payload
+	}
+
+	var current uint64
+	if r.config.schemaVersion != nil {
+		current = *r.config.schemaVersion
+	}
+
+	if version != current {
+		upgrade, ok := r.config.upgrades[version]
+		if !ok {
+			return nil, 0, fmt.Errorf("discarded value with schema version %d: %w", version, rueidis.Nil)
+		}
+
+		upgraded, err := upgrade.decode(payload)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to upgrade value from schema version %d: %w", version, err)
+		}
+
+		// NewValue checked that the upgrade returns *T.
+		return upgraded.(*T), delta, nil
+	}
+
+	var value T
+	if err := cbor.Unmarshal(payload, &value); err != nil {
+		return nil, 0, fmt.Errorf("failed to decode value: %w", err)
+	}
+	return &value, delta, nil
+}
@@ -0,0 +1,120 @@
+package rv
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/redis/rueidis"
+)
+
+type legacyProfile struct {
+	Name string
+}
+
+type profile struct {
+	FirstName string
+	LastName  string
+}
+
+func TestValueUpgradesOlderSchemaVersions(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	store := NewMemoryStore()
+
+	legacy := NewValue[legacyProfile](nil, nil, "profile", WithStore(store))
+	if err := legacy.Set(ctx, "1", &legacyProfile{Name: "Ada Lovelace"}); err != nil {
+		t.Fatalf("Set returned error: %v", err)
+	}
+
+	rawV1 := NewValue[map[string]any](nil, nil, "profile", WithStore(store), WithSchemaVersion(1))
+	if err := rawV1.Set(ctx, "2", &map[string]any{"full": "Grace Hopper"}); err != nil {
+		t.Fatalf("Set returned error: %v", err)
+	}
+
+	current := NewValue[profile](nil, nil, "profile",
+		WithStore(store),
+		WithSchemaVersion(2),
+		Upgrade(0, func(old *legacyProfile) (*profile, error) {
+			return &profile{FirstName: old.Name}, nil
+		}),
+		Upgrade(1, func(old *map[string]any) (*profile, error) {
+			return &profile{LastName: (*old)["full"].(string)}, nil
+		}),
+	)
+
+	fromV0, err := current.Get(ctx, "1")
+	if err != nil {
+		t.Fatalf("Get returned error: %v", err)
+	}
+	if fromV0.FirstName != "Ada Lovelace" {
+		t.Fatalf("unexpected upgrade from version 0: %+v", fromV0)
+	}
+
+	fromV1, err := current.Get(ctx, "2")
+	if err != nil {
+		t.Fatalf("Get returned error: %v", err)
+	}
+	if fromV1.LastName != "Grace Hopper" {
+		t.Fatalf("unexpected upgrade from version 1: %+v", fromV1)
+	}
+
+	if err := current.Set(ctx, "3", &profile{FirstName: "Alan", LastName: "Turing"}); err != nil {
+		t.Fatalf("Set returned error: %v", err)
+	}
+	fromV2, err := current.Get(ctx, "3")
+	if err != nil {
+		t.Fatalf("Get returned error: %v", err)
+	}
+	if fromV2.LastName != "Turing" {
+		t.Fatalf("unexpected round trip: %+v", fromV2)
+	}
+}
+
+func TestValueDiscardsUnknownSchemaVersions(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	store := NewMemoryStore()
+
+	legacy := NewValue[legacyProfile](nil, nil, "profile", WithStore(store))
+	if err := legacy.Set(ctx, "1", &legacyProfile{Name: "Ada"}); err != nil {
+		t.Fatalf("Set returned error: %v", err)
+	}
+
+	current := NewValue[profile](nil, nil, "profile", WithStore(store), WithSchemaVersion(1))
+	if err := current.Set(ctx, "2", &profile{FirstName: "Grace"}); err != nil {
+		t.Fatalf("Set returned error: %v", err)
+	}
+
+	if _, err := current.Get(ctx, "1"); !errors.Is(err, rueidis.Nil) {
+		t.Fatalf("expected unknown version to read as missing, got %v", err)
+	}
+
+	values, err := current.Scan(ctx, "")
+	if err != nil {
+		t.Fatalf("Scan returned error: %v", err)
+	}
+	if len(values) != 1 || values[0].FirstName != "Grace" {
+		t.Fatalf("expected Scan to skip discarded entries, got %+v", values)
+	}
+}
+
+func TestNewValueRejectsMismatchedUpgrade(t *testing.T) {
+	t.Parallel()
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected NewValue to panic for an upgrade returning another type")
+		}
+	}()
+
+	NewValue[profile](nil, nil, "profile",
+		WithStore(NewMemoryStore()),
+		WithSchemaVersion(1),
+		Upgrade(0, func(old *legacyProfile) (*legacyProfile, error) {
+			return old, nil
+		}),
+	)
+}
@@ -0,0 +1,200 @@
+package rv
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/redis/rueidis"
+)
+
+// Namespace is implemented by every Value regardless of its type parameter, letting helpers such as Script
+// address keys inside the Value's namespace.
+type Namespace interface {
+	namespacedKey(key string) string
+	redisClient() rueidis.Client
+}
+
+func (r *Value[T]) namespacedKey(key string) string {
+	return r.key + ":" + key
+}
+
+func (r *Value[T]) redisClient() rueidis.Client {
+	return r.client
+}
+
+// Script is a typed Lua script. It is loaded once per server and invoked with EVALSHA, falling back to EVAL
+// when the server reports NOSCRIPT.
+//
+// Arguments are encoded from In: a struct contributes one ARGV entry per exported field in declaration order,
+// any other type a single entry. Strings and byte slices are passed as-is, booleans as 1 or 0, numbers in
+// decimal, and everything else as CBOR, the same encoding Value uses, so scripts can store them directly.
+//
+// The reply is decoded into Out: string, []byte, []string, bool, int, int64 and float64 are converted from the
+// corresponding Redis reply, and any other type is decoded from a CBOR bulk string, such as a value written by
+// Value. A nil reply is reported with an error wrapping rueidis.Nil.
+type Script[In, Out any] struct {
+	lua *rueidis.Lua
+}
+
+// NewScript instantiates a Script from its Lua source.
+func NewScript[In, Out any](source string) *Script[In, Out] {
+	return &Script[In, Out]{lua: rueidis.NewLuaScript(source)}
+}
+
+// Run executes the script. Keys are relative to the namespace and passed to the script as KEYS with the
+// namespace prefix applied. The namespace must be backed by a Redis client.
+func (s *Script[In, Out]) Run(ctx context.Context, ns Namespace, keys []string, in *In) (*Out, error) {
+	client := ns.redisClient()
+	if client == nil {
+		return nil, errors.New("script requires a namespace backed by a Redis client")
+	}
+
+	namespaced := make([]string, len(keys))
+	for i, key := range keys {
+		namespaced[i] = ns.namespacedKey(key)
+	}
+
+	args, err := encodeScriptArgs(in)
+	if err != nil {
+		return nil, err
+	}
+
+	msg, err := s.lua.Exec(ctx, client, namespaced, args).ToMessage()
+	if err != nil {
+		return nil, fmt.Errorf("failed to run script: %w", err)
+	}
+
+	return decodeScriptResult[Out](msg)
+}
+
+// encodeScriptArgs flattens the script input into ARGV entries.
+func encodeScriptArgs[In any](in *In) ([]string, error) {
+	if in == nil {
+		return nil, nil
+	}
+
+	value := reflect.ValueOf(in).Elem()
+	if value.Kind() != reflect.Struct {
+		arg, err := encodeScriptArg(value)
+		if err != nil {
+			return nil, err
+		}
+		return []string{arg}, nil
+	}
+
+	args := make([]string, 0, value.NumField())
+	for i := range value.NumField() {
+		if !value.Type().Field(i).IsExported() {
+			continue
+		}
+
+		arg, err := encodeScriptArg(value.Field(i))
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode script argument %s: %w", value.Type().Field(i).Name, err)
+		}
+		args = append(args, arg)
+	}
+
+	return args, nil
+}
+
+func encodeScriptArg(value reflect.Value) (string, error) {
+	for value.Kind() == reflect.Pointer {
+		if value.IsNil() {
+			return "", nil
+		}
+		value = value.Elem()
+	}
+
+	switch value.Kind() {
+	case reflect.String:
+		return value.String(), nil
+	case reflect.Bool:
+		if value.Bool() {
+			return "1", nil
+		}
+		return "0", nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(value.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(value.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(value.Float(), 'f', -1, 64), nil
+	case reflect.Slice:
+		if value.Type().Elem().Kind() == reflect.Uint8 {
+			return rueidis.BinaryString(value.Bytes()), nil
+		}
+	}
+
+	encoded, err := cbor.Marshal(value.Interface())
+	if err != nil {
+		return "", err
+	}
+	return rueidis.BinaryString(encoded), nil
+}
+
+// decodeScriptResult converts the script reply into Out.
+func decodeScriptResult[Out any](msg rueidis.RedisMessage) (*Out, error) {
+	if msg.IsNil() {
+		return nil, fmt.Errorf("failed to decode script result: %w", rueidis.Nil)
+	}
+
+	var (
+		out Out
+		err error
+	)
+
+	switch target := any(&out).(type) {
+	case *string:
+		if msg.IsInt64() {
+			var n int64
+			n, err = msg.AsInt64()
+			*target = strconv.FormatInt(n, 10)
+		} else {
+			*target, err = msg.ToString()
+		}
+	case *[]byte:
+		*target, err = msg.AsBytes()
+	case *[]string:
+		*target, err = msg.AsStrSlice()
+	case *bool:
+		*target, err = msg.AsBool()
+	case *int:
+		var n int64
+		n, err = msg.AsInt64()
+		*target = int(n)
+	case *int64:
+		*target, err = msg.AsInt64()
+	case *float64:
+		*target, err = msg.AsFloat64()
+	default:
+		var data []byte
+		if data, err = msg.AsBytes(); err == nil {
+			err = cbor.Unmarshal(unwrapEnvelope(data), &out)
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode script result: %w", err)
+	}
+
+	return &out, nil
+}
+
+// unwrapEnvelope returns the payload of an enveloped value, ignoring its metadata.
+func unwrapEnvelope(data []byte) []byte {
+	if !bytes.HasPrefix(data, envelopePrefix) {
+		return data
+	}
+
+	var wrapped envelope
+	if err := cbor.Unmarshal(data[len(envelopePrefix):], &wrapped); err != nil {
+		return data
+	}
+
+	return wrapped.Payload
+}
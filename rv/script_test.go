@@ -0,0 +1,99 @@
+package rv
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/redis/rueidis"
+	rueidismock "github.com/redis/rueidis/mock"
+	"go.uber.org/mock/gomock"
+)
+
+type mergeArgs struct {
+	Expected string
+	Next     testPayload
+	Limit    int
+	Force    bool
+	internal string
+}
+
+func TestScriptRunNamespacesKeysAndEncodesArguments(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	client := rueidismock.NewClient(ctrl)
+	value := NewValue[testPayload](client, nil, "orders")
+	script := NewScript[mergeArgs, testPayload]("return redis.call('GET', KEYS[1])")
+
+	next := testPayload{Message: "merged"}
+	client.EXPECT().
+		Do(ctx, rueidismock.MatchFn(func(tokens []string) bool {
+			return len(tokens) == 8 &&
+				tokens[0] == "EVALSHA" &&
+				tokens[2] == "1" &&
+				tokens[3] == "orders:42" &&
+				tokens[4] == "v1" &&
+				tokens[5] == string(mustEncode(next)) &&
+				tokens[6] == "3" &&
+				tokens[7] == "1"
+		}, "EVALSHA orders:42")).
+		Return(rueidismock.Result(rueidismock.RedisBlobString(string(mustEncode(next)))))
+
+	result, err := script.Run(ctx, value, []string{"42"}, &mergeArgs{Expected: "v1", Next: next, Limit: 3, Force: true, internal: "skipped"})
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if result.Message != "merged" {
+		t.Fatalf("unexpected result %+v", result)
+	}
+}
+
+func TestScriptRunDecodesScalarResults(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	client := rueidismock.NewClient(ctrl)
+	value := NewValue[testPayload](client, nil, "counters")
+	script := NewScript[int64, string]("return redis.call('INCRBY', KEYS[1], ARGV[1])")
+
+	gomock.InOrder(
+		client.EXPECT().
+			Do(ctx, matchEvalshaCommand("counters:a")).
+			Return(rueidismock.Result(rueidismock.RedisInt64(5))),
+		client.EXPECT().
+			Do(ctx, matchEvalshaCommand("counters:a")).
+			Return(rueidismock.Result(rueidismock.RedisNil())),
+	)
+
+	delta := int64(5)
+	result, err := script.Run(ctx, value, []string{"a"}, &delta)
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if *result != "5" {
+		t.Fatalf("expected \"5\", got %q", *result)
+	}
+
+	if _, err := script.Run(ctx, value, []string{"a"}, &delta); !errors.Is(err, rueidis.Nil) {
+		t.Fatalf("expected redis nil error, got %v", err)
+	}
+}
+
+func TestScriptRunRequiresRedisClient(t *testing.T) {
+	t.Parallel()
+
+	value := NewValue[testPayload](nil, nil, "memory", WithStore(NewMemoryStore()))
+	script := NewScript[string, string]("return ARGV[1]")
+
+	arg := "x"
+	if _, err := script.Run(context.Background(), value, nil, &arg); err == nil {
+		t.Fatalf("expected error for a namespace without a Redis client")
+	}
+}
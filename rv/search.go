@@ -0,0 +1,267 @@
+package rv
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"iter"
+	"reflect"
+	"strings"
+
+	"github.com/redis/rueidis"
+)
+
+// ErrSearchUnsupported is returned, wrapped, by CreateIndex and Query when the server does not have the
+// RediSearch module.
+var ErrSearchUnsupported = errors.New("RediSearch module is not available")
+
+type queryConfig struct {
+	offset     int64
+	limit      int64
+	pageSize   int64
+	sortBy     string
+	descending bool
+}
+
+// QueryOption configures a Query.
+type QueryOption func(*queryConfig)
+
+// QueryOffset skips the first n matches, which are still read from the server.
+func QueryOffset(n int64) QueryOption {
+	return func(q *queryConfig) {
+		q.offset = n
+	}
+}
+
+// QueryLimit stops a query after n matches. By default, a query returns all matches.
+func QueryLimit(n int64) QueryOption {
+	return func(q *queryConfig) {
+		q.limit = n
+	}
+}
+
+// QueryPageSize sets how many matches a query fetches per round trip. The default is 100.
+func QueryPageSize(n int64) QueryOption {
+	return func(q *queryConfig) {
+		q.pageSize = n
+	}
+}
+
+// QuerySortBy sorts matches by an indexed field, which should be declared sortable.
+func QuerySortBy(field string, descending bool) QueryOption {
+	return func(q *queryConfig) {
+		q.sortBy = field
+		q.descending = descending
+	}
+}
+
+// CreateIndex creates the RediSearch index of the namespace from the rv struct tags of T, unless it already
+// exists. A tag names the field type, "text", "tag" or "numeric", optionally followed by ",sortable", and the
+// field is indexed under its JSON name:
+//
+//	type Order struct {
+//		Status string   `json:"status" rv:"tag"`
+//		Items  []string `json:"items" rv:"tag"`
+//		Total  float64  `json:"total" rv:"numeric,sortable"`
+//	}
+//
+// Only fields declared on T itself are indexed, not those of embedded structs, and slices index every element.
+// Changing the tags requires dropping the index with DropIndex first, as an existing index is left as is.
+func (j *JSONValue[T]) CreateIndex(ctx context.Context) error {
+	fields, err := searchSchema(reflect.TypeFor[T]())
+	if err != nil {
+		return err
+	}
+
+	// The builders share the command, so every field extends the same FT.CREATE and the last one builds it.
+	schema := j.client.B().FtCreate().Index(j.indexName()).OnJson().Prefix(1).Prefix(j.namespacedKey("")).Schema()
+	var cmd interface{ Build() rueidis.Completed }
+	for _, f := range fields {
+		field := schema.FieldName(f.path).As(f.name)
+		switch f.kind {
+		case "text":
+			text := field.Text()
+			if f.sortable {
+				text.Sortable()
+			}
+			cmd = text
+		case "tag":
+			tag := field.Tag()
+			if f.sortable {
+				tag.Sortable()
+			}
+			cmd = tag
+		case "numeric":
+			numeric := field.Numeric()
+			if f.sortable {
+				numeric.Sortable()
+			}
+			cmd = numeric
+		}
+	}
+
+	err = j.client.Do(ctx, cmd.Build()).Error()
+	if err != nil && !strings.Contains(strings.ToLower(err.Error()), "index already exists") {
+		return fmt.Errorf("failed to create index: %w", searchError(err))
+	}
+
+	return nil
+}
+
+// DropIndex drops the RediSearch index of the namespace, keeping the documents.
+func (j *JSONValue[T]) DropIndex(ctx context.Context) error {
+	if err := j.client.Do(ctx, j.client.B().FtDropindex().Index(j.indexName()).Build()).Error(); err != nil {
+		return fmt.Errorf("failed to drop index: %w", searchError(err))
+	}
+
+	return nil
+}
+
+// Query iterates over the keys (without the namespace prefix) and documents matching the RediSearch query,
+// such as "@status:{open} @total:[10 +inf]" or "*" for all documents. The index must have been created with
+// CreateIndex. Matches are read a page at a time through an FT.AGGREGATE cursor, so queries are not capped by
+// the MAXSEARCHRESULTS setting and the server keeps the position between pages. Documents deleted meanwhile
+// are skipped.
+//
+// The returned function reports the error that ended the last iteration early, or nil once all matches were
+// returned or the iteration was stopped.
+func (j *JSONValue[T]) Query(ctx context.Context, filter string, options ...QueryOption) (iter.Seq2[string, *T], func() error) {
+	q := queryConfig{pageSize: 100}
+	for _, opt := range options {
+		opt(&q)
+	}
+
+	var err error
+	seq := func(yield func(string, *T) bool) {
+		err = j.query(ctx, filter, q, yield)
+	}
+
+	return seq, func() error { return err }
+}
+
+func (j *JSONValue[T]) query(ctx context.Context, filter string, q queryConfig, yield func(string, *T) bool) error {
+	load := j.client.B().FtAggregate().Index(j.indexName()).Query(filter).Load(2).Field("@__key", "$")
+	if q.sortBy != "" {
+		property := load.Sortby(2).Property("@" + strings.TrimPrefix(q.sortBy, "@"))
+		if q.descending {
+			property.Desc()
+		} else {
+			property.Asc()
+		}
+	}
+
+	cursor, _, docs, err := j.client.Do(ctx, load.Withcursor().Count(q.pageSize).Dialect(2).Build()).AsFtAggregateCursor()
+	var skipped, returned int64
+	for {
+		if err != nil {
+			return fmt.Errorf("failed to query index: %w", searchError(err))
+		}
+
+		for _, doc := range docs {
+			data, ok := doc["$"]
+			if !ok {
+				continue // expired or deleted after matching
+			}
+			if skipped < q.offset {
+				skipped++
+				continue
+			}
+
+			var value T
+			if err := json.Unmarshal([]byte(data), &value); err != nil {
+				j.closeCursor(ctx, cursor)
+				return fmt.Errorf("failed to decode key %q: %w", doc["__key"], err)
+			}
+
+			returned++
+			if !yield(strings.TrimPrefix(doc["__key"], j.namespacedKey("")), &value) || returned == q.limit {
+				j.closeCursor(ctx, cursor)
+				return nil
+			}
+		}
+
+		if cursor == 0 {
+			return nil
+		}
+
+		cursor, _, docs, err = j.client.Do(ctx,
+			j.client.B().FtCursorRead().Index(j.indexName()).CursorId(cursor).Count(q.pageSize).Build(),
+		).AsFtAggregateCursor()
+	}
+}
+
+// closeCursor releases a cursor that was not read to the end. On failure, the server drops it once it idles.
+func (j *JSONValue[T]) closeCursor(ctx context.Context, cursor int64) {
+	if cursor != 0 {
+		_ = j.client.Do(context.WithoutCancel(ctx), j.client.B().FtCursorDel().Index(j.indexName()).CursorId(cursor).Build()).Error()
+	}
+}
+
+func (j *JSONValue[T]) indexName() string {
+	return "idx:" + j.key
+}
+
+// searchField is an indexed field of the SCHEMA of FT.CREATE.
+type searchField struct {
+	path     string
+	name     string
+	kind     string
+	sortable bool
+}
+
+// searchSchema returns the indexed fields for the rv struct tags of t.
+func searchSchema(t reflect.Type) ([]searchField, error) {
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("cannot index %v: not a struct", t)
+	}
+
+	var schema []searchField
+	for _, field := range reflect.VisibleFields(t) {
+		tag, ok := field.Tag.Lookup("rv")
+		if !ok || !field.IsExported() || len(field.Index) > 1 {
+			continue
+		}
+
+		kind, options, _ := strings.Cut(tag, ",")
+		switch kind {
+		case "text", "tag", "numeric":
+		default:
+			return nil, fmt.Errorf("cannot index field %s: unknown type %q", field.Name, kind)
+		}
+
+		name := field.Name
+		if jsonTag, _, _ := strings.Cut(field.Tag.Get("json"), ","); jsonTag == "-" {
+			return nil, fmt.Errorf("cannot index field %s: not encoded to JSON", field.Name)
+		} else if jsonTag != "" {
+			name = jsonTag
+		}
+
+		path := "$." + name
+		if field.Type.Kind() == reflect.Slice || field.Type.Kind() == reflect.Array {
+			path += "[*]"
+		}
+
+		switch options {
+		case "", "sortable":
+		default:
+			return nil, fmt.Errorf("cannot index field %s: unknown option %q", field.Name, options)
+		}
+		schema = append(schema, searchField{path: path, name: name, kind: kind, sortable: options == "sortable"})
+	}
+
+	if len(schema) == 0 {
+		return nil, fmt.Errorf("cannot index %v: no fields tagged with rv", t)
+	}
+
+	return schema, nil
+}
+
+// searchError replaces the error of a server without the RediSearch module with ErrSearchUnsupported.
+func searchError(err error) error {
+	if isUnknownCommand(err) {
+		return ErrSearchUnsupported
+	}
+
+	return err
+}
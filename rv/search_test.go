@@ -0,0 +1,134 @@
+package rv
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/redis/rueidis"
+	rueidismock "github.com/redis/rueidis/mock"
+	"go.uber.org/mock/gomock"
+)
+
+type testIndexedOrder struct {
+	ID     string   `json:"id"`
+	Status string   `json:"status" rv:"tag"`
+	Items  []string `json:"items" rv:"tag"`
+	Note   string   `rv:"text"`
+	Total  float64  `json:"total" rv:"numeric,sortable"`
+}
+
+func TestJSONValueCreateIndex(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	client := rueidismock.NewClient(ctrl)
+	orders := NewJSONValue[testIndexedOrder](client, "orders")
+
+	create := rueidismock.Match("FT.CREATE", "idx:orders", "ON", "JSON", "PREFIX", "1", "orders:", "SCHEMA",
+		"$.status", "AS", "status", "TAG",
+		"$.items[*]", "AS", "items", "TAG",
+		"$.Note", "AS", "Note", "TEXT",
+		"$.total", "AS", "total", "NUMERIC", "SORTABLE",
+	)
+	client.EXPECT().Do(ctx, create).Return(rueidismock.Result(rueidismock.RedisString("OK")))
+	client.EXPECT().Do(ctx, create).Return(rueidismock.Result(rueidismock.RedisError("Index already exists")))
+
+	if err := orders.CreateIndex(ctx); err != nil {
+		t.Fatalf("CreateIndex returned error: %v", err)
+	}
+	if err := orders.CreateIndex(ctx); err != nil {
+		t.Fatalf("expected an existing index to be kept, got %v", err)
+	}
+
+	if err := NewJSONValue[testOrder](client, "orders").CreateIndex(ctx); err == nil {
+		t.Fatal("expected an error for a type without indexed fields")
+	}
+}
+
+func TestJSONValueQueryReadsCursor(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	client := rueidismock.NewClient(ctrl)
+	orders := NewJSONValue[testIndexedOrder](client, "orders")
+
+	row := func(key, doc string) rueidis.RedisMessage {
+		return rueidismock.RedisArray(
+			rueidismock.RedisBlobString("__key"), rueidismock.RedisBlobString(key),
+			rueidismock.RedisBlobString("$"), rueidismock.RedisBlobString(doc),
+		)
+	}
+
+	client.EXPECT().
+		Do(ctx, rueidismock.Match("FT.AGGREGATE", "idx:orders", "@status:{open}", "LOAD", "2", "@__key", "$",
+			"SORTBY", "2", "@total", "DESC", "WITHCURSOR", "COUNT", "2", "DIALECT", "2")).
+		Return(rueidismock.Result(rueidismock.RedisArray(
+			rueidismock.RedisArray(
+				rueidismock.RedisInt64(5),
+				row("orders:1", `{"id":"1","total":40}`),
+				row("orders:2", `{"id":"2","total":30}`),
+			),
+			rueidismock.RedisInt64(7),
+		)))
+	client.EXPECT().
+		Do(ctx, rueidismock.Match("FT.CURSOR", "READ", "idx:orders", "7", "COUNT", "2")).
+		Return(rueidismock.Result(rueidismock.RedisArray(
+			rueidismock.RedisArray(
+				rueidismock.RedisInt64(5),
+				row("orders:3", `{"id":"3","total":20}`),
+				row("orders:4", `{"id":"4","total":10}`),
+			),
+			rueidismock.RedisInt64(7),
+		)))
+	client.EXPECT().
+		Do(gomock.Any(), rueidismock.Match("FT.CURSOR", "DEL", "idx:orders", "7")).
+		Return(rueidismock.Result(rueidismock.RedisString("OK")))
+
+	var (
+		keys   []string
+		totals []float64
+	)
+	matches, errFunc := orders.Query(ctx, "@status:{open}", QuerySortBy("total", true), QueryPageSize(2), QueryOffset(1), QueryLimit(2))
+	for key, order := range matches {
+		keys = append(keys, key)
+		totals = append(totals, order.Total)
+	}
+	if err := errFunc(); err != nil {
+		t.Fatalf("Query returned error: %v", err)
+	}
+
+	if !slices.Equal(keys, []string{"2", "3"}) || !slices.Equal(totals, []float64{30, 20}) {
+		t.Fatalf("unexpected matches: %v %v", keys, totals)
+	}
+}
+
+func TestJSONValueQueryWithoutModule(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	client := rueidismock.NewClient(ctrl)
+	orders := NewJSONValue[testIndexedOrder](client, "orders")
+
+	client.EXPECT().
+		Do(ctx, rueidismock.MatchFn(func(tokens []string) bool { return tokens[0] == "FT.AGGREGATE" }, "FT.AGGREGATE")).
+		Return(rueidismock.Result(rueidismock.RedisError("ERR unknown command 'FT.AGGREGATE'")))
+
+	matches, errFunc := orders.Query(ctx, "*")
+	for range matches {
+		t.Fatal("expected no matches")
+	}
+	if err := errFunc(); !errors.Is(err, ErrSearchUnsupported) {
+		t.Fatalf("expected ErrSearchUnsupported, got %v", err)
+	}
+}
@@ -0,0 +1,207 @@
+package rv
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/redis/rueidis"
+)
+
+var (
+	// ErrSemaphoreFull is returned by TryAcquire when every slot of the semaphore is held.
+	ErrSemaphoreFull = errors.New("semaphore is full")
+	// ErrLeaseLost is returned when a lease has expired or was released before the operation.
+	ErrLeaseLost = errors.New("lease lost")
+)
+
+// semaphoreAcquireScript drops expired holders and, if a slot is free, registers the caller
+// with a fresh fencing token. Redis' own clock is used so holders never disagree on expiry.
+var semaphoreAcquireScript = rueidis.NewLuaScript(`
+local t = redis.call('TIME')
+local now = t[1] * 1000 + math.floor(t[2] / 1000)
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', now)
+if redis.call('ZCARD', KEYS[1]) >= tonumber(ARGV[3]) then
+	return false
+end
+local token = redis.call('INCR', KEYS[2])
+redis.call('ZADD', KEYS[1], now + tonumber(ARGV[2]), ARGV[1])
+return token
+`)
+
+// semaphoreRenewScript extends the expiry of a holder that has not expired yet.
+var semaphoreRenewScript = rueidis.NewLuaScript(`
+local t = redis.call('TIME')
+local now = t[1] * 1000 + math.floor(t[2] / 1000)
+local expiry = redis.call('ZSCORE', KEYS[1], ARGV[1])
+if not expiry or tonumber(expiry) <= now then
+	redis.call('ZREM', KEYS[1], ARGV[1])
+	return 0
+end
+redis.call('ZADD', KEYS[1], 'XX', now + tonumber(ARGV[2]), ARGV[1])
+return 1
+`)
+
+// Semaphore is a distributed counting semaphore allowing up to a fixed number of concurrent holders.
+// Each acquisition yields a Lease carrying a monotonically increasing fencing token.
+type Semaphore struct {
+	client rueidis.Client
+	key    string
+	limit  int64
+	ttl    time.Duration
+}
+
+// NewSemaphore instantiates a semaphore named by key that admits up to limit holders.
+// Leases expire after ttl unless renewed.
+func NewSemaphore(client rueidis.Client, key string, limit int64, ttl time.Duration) *Semaphore {
+	return &Semaphore{client: client, key: key, limit: limit, ttl: ttl}
+}
+
+// TryAcquire acquires a slot without waiting. It returns ErrSemaphoreFull when no slot is free.
+func (s *Semaphore) TryAcquire(ctx context.Context) (*Lease, error) {
+	id := rand.Text()
+	started := time.Now()
+
+	token, err := semaphoreAcquireScript.Exec(ctx, s.client, s.keys(), []string{
+		id,
+		strconv.FormatInt(s.ttl.Milliseconds(), 10),
+		strconv.FormatInt(s.limit, 10),
+	}).AsInt64()
+	if err != nil {
+		if rueidis.IsRedisNil(err) {
+			return nil, ErrSemaphoreFull
+		}
+		return nil, fmt.Errorf("failed to acquire semaphore: %w", err)
+	}
+
+	lease := &Lease{sem: s, id: id, token: token, done: make(chan struct{})}
+
+	lease.mu.Lock()
+	lease.timer = time.AfterFunc(s.ttl-time.Since(started), lease.expire)
+	lease.mu.Unlock()
+
+	return lease, nil
+}
+
+// Acquire waits until a slot is free or the context is done, polling with the given interval,
+// which must be positive.
+func (s *Semaphore) Acquire(ctx context.Context, interval time.Duration) (*Lease, error) {
+	if interval <= 0 {
+		return nil, fmt.Errorf("poll interval %s must be positive", interval)
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		lease, err := s.TryAcquire(ctx)
+		if !errors.Is(err, ErrSemaphoreFull) {
+			return lease, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("failed to acquire semaphore: %w", ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+// keys returns the holder set and fencing counter keys, hash-tagged to share a cluster slot.
+func (s *Semaphore) keys() []string {
+	return []string{"{" + s.key + "}:holders", "{" + s.key + "}:fence"}
+}
+
+// Lease is a slot held in a Semaphore. It must be renewed before its TTL elapses to stay valid.
+type Lease struct {
+	sem   *Semaphore
+	id    string
+	token int64
+
+	mu    sync.Mutex
+	timer *time.Timer
+	done  chan struct{}
+	once  sync.Once
+}
+
+// ID returns the unique identifier of the lease holder.
+func (l *Lease) ID() string {
+	return l.id
+}
+
+// Token returns the fencing token of the lease. Tokens increase with every acquisition of the semaphore,
+// so downstream systems can reject writes carrying a token older than one they have already seen.
+func (l *Lease) Token() int64 {
+	return l.token
+}
+
+// Done returns a channel that is closed once the lease expires, is released, or fails to renew.
+func (l *Lease) Done() <-chan struct{} {
+	return l.done
+}
+
+// Renew extends the lease by the semaphore TTL. It returns ErrLeaseLost if the lease already expired.
+func (l *Lease) Renew(ctx context.Context) error {
+	select {
+	case <-l.done:
+		return ErrLeaseLost
+	default:
+	}
+
+	started := time.Now()
+
+	renewed, err := semaphoreRenewScript.Exec(ctx, l.sem.client, l.sem.keys()[:1], []string{
+		l.id,
+		strconv.FormatInt(l.sem.ttl.Milliseconds(), 10),
+	}).AsInt64()
+	if err != nil {
+		return fmt.Errorf("failed to renew lease: %w", err)
+	}
+	if renewed == 0 {
+		l.expire()
+		return ErrLeaseLost
+	}
+
+	l.mu.Lock()
+	select {
+	case <-l.done:
+		// The lease expired locally while renewing, so its holder already stopped; give the renewed slot back.
+		l.mu.Unlock()
+		if err := l.sem.client.Do(ctx, l.sem.client.B().Zrem().Key(l.sem.keys()[0]).Member(l.id).Build()).Error(); err != nil {
+			return fmt.Errorf("%w: failed to release it: %w", ErrLeaseLost, err)
+		}
+		return ErrLeaseLost
+	default:
+	}
+	l.timer.Reset(l.sem.ttl - time.Since(started))
+	l.mu.Unlock()
+
+	return nil
+}
+
+// Release gives the slot back to the semaphore and closes Done.
+func (l *Lease) Release(ctx context.Context) error {
+	l.expire()
+
+	err := l.sem.client.Do(ctx, l.sem.client.B().Zrem().Key(l.sem.keys()[0]).Member(l.id).Build()).Error()
+	if err != nil {
+		return fmt.Errorf("failed to release lease: %w", err)
+	}
+
+	return nil
+}
+
+// expire closes Done. It holds mu while doing so, so Renew never extends the timer of an expired lease.
+func (l *Lease) expire() {
+	l.once.Do(func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+
+		l.timer.Stop()
+		close(l.done)
+	})
+}
@@ -0,0 +1,172 @@
+package rv
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/redis/rueidis"
+	rueidismock "github.com/redis/rueidis/mock"
+	"go.uber.org/mock/gomock"
+)
+
+func TestSemaphoreTryAcquireReturnsFencingToken(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	client := rueidismock.NewClient(ctrl)
+	semaphore := NewSemaphore(client, "downstream", 3, time.Minute)
+
+	client.EXPECT().
+		Do(ctx, matchEvalshaCommand("{downstream}:holders", "{downstream}:fence")).
+		Return(rueidismock.Result(rueidismock.RedisInt64(42)))
+	client.EXPECT().
+		Do(ctx, rueidismock.MatchFn(func(tokens []string) bool {
+			return len(tokens) == 3 && tokens[0] == "ZREM" && tokens[1] == "{downstream}:holders"
+		}, "ZREM holder")).
+		Return(rueidismock.Result(rueidismock.RedisInt64(1)))
+
+	lease, err := semaphore.TryAcquire(ctx)
+	if err != nil {
+		t.Fatalf("TryAcquire returned error: %v", err)
+	}
+	if lease.Token() != 42 {
+		t.Fatalf("expected token 42, got %d", lease.Token())
+	}
+
+	if err := lease.Release(ctx); err != nil {
+		t.Fatalf("Release returned error: %v", err)
+	}
+
+	select {
+	case <-lease.Done():
+	default:
+		t.Fatalf("expected Done to be closed after Release")
+	}
+	if err := lease.Renew(ctx); !errors.Is(err, ErrLeaseLost) {
+		t.Fatalf("expected ErrLeaseLost after Release, got %v", err)
+	}
+}
+
+func TestSemaphoreTryAcquireReportsFull(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	client := rueidismock.NewClient(ctrl)
+	semaphore := NewSemaphore(client, "busy", 1, time.Minute)
+
+	client.EXPECT().
+		Do(ctx, matchEvalshaCommand("{busy}:holders", "{busy}:fence")).
+		Return(rueidismock.Result(rueidismock.RedisNil()))
+
+	if _, err := semaphore.TryAcquire(ctx); !errors.Is(err, ErrSemaphoreFull) {
+		t.Fatalf("expected ErrSemaphoreFull, got %v", err)
+	}
+}
+
+func TestLeaseRenewDetectsLostLease(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	client := rueidismock.NewClient(ctrl)
+	semaphore := NewSemaphore(client, "renew", 1, time.Minute)
+
+	gomock.InOrder(
+		client.EXPECT().
+			Do(ctx, matchEvalshaCommand("{renew}:holders", "{renew}:fence")).
+			Return(rueidismock.Result(rueidismock.RedisInt64(1))),
+		client.EXPECT().
+			Do(ctx, matchEvalshaCommand("{renew}:holders")).
+			Return(rueidismock.Result(rueidismock.RedisInt64(0))),
+	)
+
+	lease, err := semaphore.TryAcquire(ctx)
+	if err != nil {
+		t.Fatalf("TryAcquire returned error: %v", err)
+	}
+
+	if err := lease.Renew(ctx); !errors.Is(err, ErrLeaseLost) {
+		t.Fatalf("expected ErrLeaseLost, got %v", err)
+	}
+
+	select {
+	case <-lease.Done():
+	default:
+		t.Fatalf("expected Done to be closed after losing the lease")
+	}
+}
+
+func TestLeaseRenewReleasesSlotAfterLocalExpiry(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	client := rueidismock.NewClient(ctrl)
+	semaphore := NewSemaphore(client, "renew", 1, 20*time.Millisecond)
+
+	gomock.InOrder(
+		client.EXPECT().
+			Do(ctx, matchEvalshaCommand("{renew}:holders", "{renew}:fence")).
+			Return(rueidismock.Result(rueidismock.RedisInt64(1))),
+		client.EXPECT().
+			Do(ctx, matchEvalshaCommand("{renew}:holders")).
+			DoAndReturn(func(context.Context, rueidis.Completed) rueidis.RedisResult {
+				// The lease expires locally while the server renews it.
+				time.Sleep(50 * time.Millisecond)
+				return rueidismock.Result(rueidismock.RedisInt64(1))
+			}),
+		client.EXPECT().
+			Do(ctx, rueidismock.MatchFn(func(tokens []string) bool {
+				return len(tokens) == 3 && tokens[0] == "ZREM" && tokens[1] == "{renew}:holders"
+			}, "ZREM lease")).
+			Return(rueidismock.Result(rueidismock.RedisInt64(1))),
+	)
+
+	lease, err := semaphore.TryAcquire(ctx)
+	if err != nil {
+		t.Fatalf("TryAcquire returned error: %v", err)
+	}
+
+	if err := lease.Renew(ctx); !errors.Is(err, ErrLeaseLost) {
+		t.Fatalf("expected ErrLeaseLost, got %v", err)
+	}
+}
+
+func matchEvalshaCommand(keys ...string) gomock.Matcher {
+	return rueidismock.MatchFn(func(tokens []string) bool {
+		if len(tokens) < 3+len(keys) || tokens[0] != "EVALSHA" || tokens[2] != fmt.Sprint(len(keys)) {
+			return false
+		}
+		for i, key := range keys {
+			if tokens[3+i] != key {
+				return false
+			}
+		}
+		return true
+	}, fmt.Sprintf("EVALSHA keys=%v", keys))
+}
+
+func TestSemaphoreAcquireRejectsNonPositiveInterval(t *testing.T) {
+	t.Parallel()
+
+	sem := NewSemaphore(nil, "jobs", 1, time.Second)
+
+	for _, interval := range []time.Duration{0, -time.Second} {
+		if _, err := sem.Acquire(context.Background(), interval); err == nil {
+			t.Errorf("expected error for interval %s", interval)
+		}
+	}
+}
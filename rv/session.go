@@ -0,0 +1,261 @@
+package rv
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/redis/rueidis"
+)
+
+// ErrNoSession is returned when a request carries no session cookie, an invalid one, or one for an expired session.
+var ErrNoSession = errors.New("no session")
+
+// Session is a server-side HTTP session holding typed data.
+type Session[T any] struct {
+	ID        string
+	UserID    string
+	CreatedAt time.Time
+	Data      T
+}
+
+// SessionManager issues signed session cookies and stores the sessions in Redis.
+// Sessions expire after being idle for the configured TTL; every Load slides the expiration forward.
+// The sessions of every user are indexed in a Redis set, which DestroyUser reads, so sessions with a user ID
+// require the client even with WithStore.
+type SessionManager[T any] struct {
+	client   rueidis.Client
+	sessions *Value[Session[T]]
+	index    Namespace
+	secret   []byte
+	cookie   http.Cookie
+	ttl      time.Duration
+}
+
+// NewSessionManager instantiates a SessionManager for the provided key prefix.
+// Session IDs are signed with secret. The cookie template configures the cookie name, path, domain and
+// security attributes; its value and expiry are managed by the SessionManager. An empty name defaults to "session".
+func NewSessionManager[T any](client rueidis.Client, key string, secret []byte, cookie http.Cookie, ttl time.Duration, options ...Option) *SessionManager[T] {
+	if cookie.Name == "" {
+		cookie.Name = "session"
+	}
+
+	return &SessionManager[T]{
+		client:   client,
+		sessions: NewValue[Session[T]](client, nil, key+":session", options...),
+		index:    NewValue[string](client, nil, key+":user", options...),
+		secret:   secret,
+		cookie:   cookie,
+		ttl:      ttl,
+	}
+}
+
+// Create starts a new session for userID and issues its cookie.
+func (m *SessionManager[T]) Create(ctx context.Context, w http.ResponseWriter, userID string, data *T) (*Session[T], error) {
+	session := &Session[T]{
+		ID:        rand.Text(),
+		UserID:    userID,
+		CreatedAt: time.Now(),
+	}
+	if data != nil {
+		session.Data = *data
+	}
+
+	if err := m.store(ctx, session); err != nil {
+		return nil, err
+	}
+
+	m.setCookie(w, session.ID, int(m.ttl/time.Second))
+
+	return session, nil
+}
+
+// Load returns the session referenced by the request's cookie and slides its expiration forward.
+// It returns ErrNoSession when the cookie is missing, its signature is invalid, or the session expired.
+func (m *SessionManager[T]) Load(w http.ResponseWriter, r *http.Request) (*Session[T], error) {
+	cookie, err := r.Cookie(m.cookie.Name)
+	if err != nil {
+		return nil, ErrNoSession
+	}
+
+	id, ok := m.verify(cookie.Value)
+	if !ok {
+		return nil, ErrNoSession
+	}
+
+	session, err := m.sessions.Get(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, rueidis.Nil) {
+			return nil, ErrNoSession
+		}
+		return nil, fmt.Errorf("failed to load session: %w", err)
+	}
+
+	if err := m.touch(r.Context(), session); err != nil {
+		if errors.Is(err, rueidis.Nil) {
+			return nil, ErrNoSession
+		}
+		return nil, err
+	}
+
+	m.setCookie(w, session.ID, int(m.ttl/time.Second))
+
+	return session, nil
+}
+
+// Save persists changes to the session data.
+func (m *SessionManager[T]) Save(ctx context.Context, session *Session[T]) error {
+	return m.store(ctx, session)
+}
+
+// Regenerate replaces the session with a new one under a fresh ID, carrying over its data, and binds it to userID.
+// Call it on login and privilege changes to prevent session fixation.
+func (m *SessionManager[T]) Regenerate(ctx context.Context, w http.ResponseWriter, session *Session[T], userID string) (*Session[T], error) {
+	regenerated, err := m.Create(ctx, w, userID, &session.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := m.remove(ctx, session); err != nil {
+		return nil, err
+	}
+
+	return regenerated, nil
+}
+
+// Destroy deletes the session and expires its cookie.
+func (m *SessionManager[T]) Destroy(ctx context.Context, w http.ResponseWriter, session *Session[T]) error {
+	if err := m.remove(ctx, session); err != nil {
+		return err
+	}
+
+	m.setCookie(w, "", -1)
+
+	return nil
+}
+
+// DestroyUser deletes every session of userID, logging the user out everywhere.
+func (m *SessionManager[T]) DestroyUser(ctx context.Context, userID string) error {
+	userKey := m.userKey(userID)
+
+	ids, err := m.client.Do(ctx, m.client.B().Smembers().Key(userKey).Build()).AsStrSlice()
+	if err != nil {
+		return fmt.Errorf("failed to list sessions of user %q: %w", userID, err)
+	}
+
+	for _, id := range ids {
+		if err := m.sessions.Delete(ctx, id); err != nil {
+			return fmt.Errorf("failed to delete session: %w", err)
+		}
+	}
+
+	if err := m.client.Do(ctx, m.client.B().Del().Key(userKey).Build()).Error(); err != nil {
+		return fmt.Errorf("failed to unindex sessions of user %q: %w", userID, err)
+	}
+
+	return nil
+}
+
+// store writes the session with a fresh TTL and indexes it.
+func (m *SessionManager[T]) store(ctx context.Context, session *Session[T]) error {
+	if err := m.sessions.Set(ctx, session.ID, session, SetTTL(m.ttl)); err != nil {
+		return fmt.Errorf("failed to store session: %w", err)
+	}
+
+	if err := m.indexSession(ctx, session); err != nil {
+		return fmt.Errorf("failed to index session: %w", err)
+	}
+
+	return nil
+}
+
+// touch slides the expiration of the session and its user index forward without rewriting the session, so it
+// does not overwrite a concurrent Save. A session that expired meanwhile is reported with rueidis.Nil.
+func (m *SessionManager[T]) touch(ctx context.Context, session *Session[T]) error {
+	if err := m.sessions.Expire(ctx, session.ID, m.ttl); err != nil {
+		return fmt.Errorf("failed to refresh session: %w", err)
+	}
+
+	if err := m.indexSession(ctx, session); err != nil {
+		return fmt.Errorf("failed to refresh session index: %w", err)
+	}
+
+	return nil
+}
+
+// indexSession adds the session to the set of its user and extends the set's TTL to that of the session, so the
+// set outlives every session it lists. IDs of sessions that expired on their own are removed by DestroyUser.
+func (m *SessionManager[T]) indexSession(ctx context.Context, session *Session[T]) error {
+	if session.UserID == "" {
+		return nil
+	}
+
+	userKey := m.userKey(session.UserID)
+	for _, resp := range m.client.DoMulti(ctx,
+		m.client.B().Sadd().Key(userKey).Member(session.ID).Build(),
+		m.client.B().Pexpire().Key(userKey).Milliseconds(m.ttl.Milliseconds()).Build(),
+	) {
+		if err := resp.Error(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m *SessionManager[T]) remove(ctx context.Context, session *Session[T]) error {
+	if err := m.sessions.Delete(ctx, session.ID); err != nil {
+		return fmt.Errorf("failed to delete session: %w", err)
+	}
+
+	if session.UserID != "" {
+		err := m.client.Do(ctx, m.client.B().Srem().Key(m.userKey(session.UserID)).Member(session.ID).Build()).Error()
+		if err != nil {
+			return fmt.Errorf("failed to unindex session: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// userKey returns the key of the set indexing the sessions of userID.
+func (m *SessionManager[T]) userKey(userID string) string {
+	return m.index.namespacedKey(EscapeKey(userID))
+}
+
+func (m *SessionManager[T]) setCookie(w http.ResponseWriter, id string, maxAge int) {
+	cookie := m.cookie
+	cookie.MaxAge = maxAge
+	if id != "" {
+		cookie.Value = id + "." + m.sign(id)
+	}
+
+	http.SetCookie(w, &cookie)
+}
+
+func (m *SessionManager[T]) sign(id string) string {
+	mac := hmac.New(sha256.New, m.secret)
+	mac.Write([]byte(id))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// verify checks the cookie signature and returns the session ID it carries.
+func (m *SessionManager[T]) verify(value string) (string, bool) {
+	id, signature, ok := strings.Cut(value, ".")
+	if !ok || id == "" {
+		return "", false
+	}
+
+	if !hmac.Equal([]byte(signature), []byte(m.sign(id))) {
+		return "", false
+	}
+
+	return id, true
+}
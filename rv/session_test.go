@@ -0,0 +1,178 @@
+package rv
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/redis/rueidis"
+	rueidismock "github.com/redis/rueidis/mock"
+	"go.uber.org/mock/gomock"
+)
+
+func TestSessionManagerCreateIssuesSignedCookie(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	client := rueidismock.NewClient(ctrl)
+	manager := NewSessionManager[testPayload](client, "app", []byte("secret"), http.Cookie{HttpOnly: true}, time.Hour)
+
+	gomock.InOrder(
+		client.EXPECT().
+			Do(ctx, rueidismock.MatchFn(func(tokens []string) bool {
+				return tokens[0] == "SET" && strings.HasPrefix(tokens[1], "app:session:") &&
+					hasTokenSequence(tokens, "PX", "3600000")
+			}, "SET session")).
+			Return(rueidismock.Result(rueidismock.RedisString("OK"))),
+		client.EXPECT().
+			DoMulti(ctx,
+				rueidismock.MatchFn(func(tokens []string) bool {
+					return tokens[0] == "SADD" && tokens[1] == "app:user:user-1"
+				}, "SADD session index"),
+				rueidismock.Match("PEXPIRE", "app:user:user-1", "3600000"),
+			).
+			Return([]rueidis.RedisResult{
+				rueidismock.Result(rueidismock.RedisInt64(1)),
+				rueidismock.Result(rueidismock.RedisInt64(1)),
+			}),
+	)
+
+	response := httptest.NewRecorder()
+	session, err := manager.Create(ctx, response, "user-1", &testPayload{Message: "cart"})
+	if err != nil {
+		t.Fatalf("Create returned error: %v", err)
+	}
+
+	cookies := response.Result().Cookies()
+	if len(cookies) != 1 {
+		t.Fatalf("expected one cookie, got %d", len(cookies))
+	}
+	if cookies[0].Name != "session" || !cookies[0].HttpOnly || cookies[0].MaxAge != 3600 {
+		t.Fatalf("unexpected cookie %+v", cookies[0])
+	}
+
+	id, ok := manager.verify(cookies[0].Value)
+	if !ok || id != session.ID {
+		t.Fatalf("expected cookie to carry a valid signature for %q", session.ID)
+	}
+}
+
+func TestSessionManagerLoadRejectsTamperedCookie(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	client := rueidismock.NewClient(ctrl)
+	manager := NewSessionManager[testPayload](client, "app", []byte("secret"), http.Cookie{}, time.Hour)
+	forger := NewSessionManager[testPayload](client, "app", []byte("other"), http.Cookie{}, time.Hour)
+
+	request := httptest.NewRequest(http.MethodGet, "/", nil)
+	request.AddCookie(&http.Cookie{Name: "session", Value: "victim." + forger.sign("victim")})
+
+	if _, err := manager.Load(httptest.NewRecorder(), request); !errors.Is(err, ErrNoSession) {
+		t.Fatalf("expected ErrNoSession, got %v", err)
+	}
+}
+
+func TestSessionManagerLoadReportsExpiredSession(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	client := rueidismock.NewClient(ctrl)
+	manager := NewSessionManager[testPayload](client, "app", []byte("secret"), http.Cookie{}, time.Hour)
+
+	client.EXPECT().
+		Do(gomock.Any(), matchGetCommand("app:session:gone")).
+		Return(rueidismock.Result(rueidismock.RedisNil()))
+
+	request := httptest.NewRequest(http.MethodGet, "/", nil)
+	request.AddCookie(&http.Cookie{Name: "session", Value: "gone." + manager.sign("gone")})
+
+	if _, err := manager.Load(httptest.NewRecorder(), request); !errors.Is(err, ErrNoSession) {
+		t.Fatalf("expected ErrNoSession, got %v", err)
+	}
+}
+
+func TestSessionManagerLoadSlidesExpirationWithoutRewriting(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	client := rueidismock.NewClient(ctrl)
+	manager := NewSessionManager[testPayload](client, "app", []byte("secret"), http.Cookie{}, time.Hour)
+
+	session := Session[testPayload]{ID: "s1", UserID: "user-1", Data: testPayload{Message: "cart"}}
+
+	gomock.InOrder(
+		client.EXPECT().
+			Do(gomock.Any(), matchGetCommand("app:session:s1")).
+			Return(rueidismock.Result(rueidismock.RedisBlobString(string(mustMarshal(t, session))))),
+		client.EXPECT().
+			DoMulti(gomock.Any(), rueidismock.Match("PEXPIRE", "app:session:s1", "3600000")).
+			Return([]rueidis.RedisResult{rueidismock.Result(rueidismock.RedisInt64(1))}),
+		client.EXPECT().
+			DoMulti(gomock.Any(),
+				rueidismock.Match("SADD", "app:user:user-1", "s1"),
+				rueidismock.Match("PEXPIRE", "app:user:user-1", "3600000"),
+			).
+			Return([]rueidis.RedisResult{
+				rueidismock.Result(rueidismock.RedisInt64(0)),
+				rueidismock.Result(rueidismock.RedisInt64(1)),
+			}),
+	)
+
+	request := httptest.NewRequest(http.MethodGet, "/", nil)
+	request.AddCookie(&http.Cookie{Name: "session", Value: "s1." + manager.sign("s1")})
+
+	loaded, err := manager.Load(httptest.NewRecorder(), request)
+	if err != nil {
+		t.Fatalf("Load returned error: %v", err)
+	}
+	if loaded.Data.Message != "cart" {
+		t.Fatalf("unexpected session %+v", loaded)
+	}
+}
+
+func TestSessionManagerDestroyUserReadsUserIndex(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	client := rueidismock.NewClient(ctrl)
+	manager := NewSessionManager[testPayload](client, "app", []byte("secret"), http.Cookie{}, time.Hour)
+
+	gomock.InOrder(
+		client.EXPECT().
+			Do(ctx, rueidismock.Match("SMEMBERS", "app:user:user%2A")).
+			Return(rueidismock.Result(rueidismock.RedisArray(
+				rueidismock.RedisBlobString("s1"),
+				rueidismock.RedisBlobString("s2"),
+			))),
+		client.EXPECT().
+			Do(ctx, rueidismock.Match("DEL", "app:session:s1")).
+			Return(rueidismock.Result(rueidismock.RedisInt64(1))),
+		client.EXPECT().
+			Do(ctx, rueidismock.Match("DEL", "app:session:s2")).
+			Return(rueidismock.Result(rueidismock.RedisInt64(0))),
+		client.EXPECT().
+			Do(ctx, rueidismock.Match("DEL", "app:user:user%2A")).
+			Return(rueidismock.Result(rueidismock.RedisInt64(1))),
+	)
+
+	if err := manager.DestroyUser(ctx, "user*"); err != nil {
+		t.Fatalf("DestroyUser returned error: %v", err)
+	}
+}
@@ -0,0 +1,272 @@
+package rv
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/redis/rueidis"
+)
+
+// Store is the storage backend behind Value. Keys passed to a Store are already namespaced.
+// Missing keys are reported with rueidis.Nil so callers can treat every backend like Redis.
+type Store interface {
+	// Get returns the value stored under key.
+	Get(ctx context.Context, key string) ([]byte, error)
+	// GetTTL returns the value stored under key like Get, together with its remaining TTL like TTL, in a single
+	// round trip.
+	GetTTL(ctx context.Context, key string) ([]byte, time.Duration, error)
+	// MGet returns the values stored under keys. Missing keys are omitted from the result.
+	MGet(ctx context.Context, keys []string) (map[string][]byte, error)
+	// Set stores value under key. When an NX or XX condition is not met, it returns rueidis.Nil.
+	Set(ctx context.Context, key string, value []byte, args SetArgs) error
+	// Delete removes keys and returns how many existed.
+	Delete(ctx context.Context, keys ...string) (int64, error)
+	// Unlink removes keys like Delete, reclaiming their memory in the background.
+	Unlink(ctx context.Context, keys ...string) (int64, error)
+	// Expire sets the TTL of keys and returns how many existed.
+	Expire(ctx context.Context, ttl time.Duration, keys ...string) (int64, error)
+	// TTL returns the remaining TTL of key, or a negative duration if the key does not expire.
+	TTL(ctx context.Context, key string) (time.Duration, error)
+	// TTLs returns the remaining TTLs of keys like TTL, in a single round trip. Missing keys are omitted.
+	TTLs(ctx context.Context, keys []string) (map[string]time.Duration, error)
+	// Scan returns a page of keys matching the glob pattern and the cursor of the next page, 0 once done.
+	// Count hints how many keys to examine per page; 0 uses the backend default.
+	Scan(ctx context.Context, cursor uint64, match string, count int64) ([]string, uint64, error)
+	// CompareAndSwap replaces the value of key with value if key currently holds old, or deletes key when value is
+	// nil, and reports whether it did. The NX and XX conditions of args are ignored.
+	CompareAndSwap(ctx context.Context, key string, old, value []byte, args SetArgs) (bool, error)
+}
+
+// SetArgs are the resolved conditions and expiration of a Store.Set call.
+type SetArgs struct {
+	// TTL is the expiration of the key. Zero means no expiration.
+	TTL time.Duration
+	// KeepTTL retains the TTL of an existing key.
+	KeepTTL bool
+	// NX only sets the key if it does not exist.
+	NX bool
+	// XX only sets the key if it already exists.
+	XX bool
+}
+
+// WithStore replaces the Redis client as the storage backend, e.g. with a MemoryStore in tests.
+func WithStore(store Store) Option {
+	return func(r *valueConfig) {
+		r.store = store
+	}
+}
+
+// compareAndSwapScript replaces the value of KEYS[1] with ARGV[2] if it holds ARGV[1], or deletes it when ARGV[3]
+// is "true". The new value expires after ARGV[4] milliseconds, or keeps the current TTL if ARGV[5] is "1".
+var compareAndSwapScript = rueidis.NewLuaScript(`
+if redis.call('GET', KEYS[1]) ~= ARGV[1] then
+	return 0
+end
+if ARGV[3] == 'true' then
+	redis.call('DEL', KEYS[1])
+elseif tonumber(ARGV[4]) > 0 then
+	redis.call('SET', KEYS[1], ARGV[2], 'PX', ARGV[4])
+elseif ARGV[5] == '1' then
+	redis.call('SET', KEYS[1], ARGV[2], 'KEEPTTL')
+else
+	redis.call('SET', KEYS[1], ARGV[2])
+end
+return 1
+`)
+
+// NewRedisStore returns a Store backed by a rueidis client. It is the default backend of NewValue.
+func NewRedisStore(client rueidis.Client) Store {
+	return &redisStore{client: client}
+}
+
+type redisStore struct {
+	client rueidis.Client
+}
+
+func (s *redisStore) Get(ctx context.Context, key string) ([]byte, error) {
+	return s.client.Do(ctx, s.client.B().Get().Key(key).Build()).AsBytes()
+}
+
+func (s *redisStore) GetTTL(ctx context.Context, key string) ([]byte, time.Duration, error) {
+	resps := s.client.DoMulti(ctx,
+		s.client.B().Get().Key(key).Build(),
+		s.client.B().Pttl().Key(key).Build(),
+	)
+
+	value, err := resps[0].AsBytes()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// The key may expire between both commands, which PTTL reports like any other miss.
+	ttl, err := pttl(resps[1])
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return value, ttl, nil
+}
+
+func (s *redisStore) MGet(ctx context.Context, keys []string) (map[string][]byte, error) {
+	batch, err := rueidis.MGet(s.client, ctx, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[string][]byte, len(batch))
+	for key, msg := range batch {
+		data, err := msg.AsBytes()
+		if err != nil {
+			if rueidis.IsRedisNil(err) {
+				continue // key disappeared or never existed
+			}
+			return nil, err
+		}
+		values[key] = data
+	}
+
+	return values, nil
+}
+
+func (s *redisStore) Set(ctx context.Context, key string, value []byte, args SetArgs) error {
+	return s.client.Do(ctx, setCommand(s.client.B(), key, value, args)).Error()
+}
+
+func (s *redisStore) Delete(ctx context.Context, keys ...string) (int64, error) {
+	if len(keys) == 1 {
+		return s.client.Do(ctx, s.client.B().Del().Key(keys[0]).Build()).AsInt64()
+	}
+
+	cmds := make(rueidis.Commands, len(keys))
+	for i, key := range keys {
+		cmds[i] = s.client.B().Del().Key(key).Build()
+	}
+
+	return s.sum(ctx, cmds)
+}
+
+func (s *redisStore) Unlink(ctx context.Context, keys ...string) (int64, error) {
+	cmds := make(rueidis.Commands, len(keys))
+	for i, key := range keys {
+		cmds[i] = s.client.B().Unlink().Key(key).Build()
+	}
+
+	return s.sum(ctx, cmds)
+}
+
+func (s *redisStore) Expire(ctx context.Context, ttl time.Duration, keys ...string) (int64, error) {
+	cmds := make(rueidis.Commands, len(keys))
+	for i, key := range keys {
+		cmds[i] = s.client.B().Pexpire().Key(key).Milliseconds(ttl.Milliseconds()).Build()
+	}
+
+	return s.sum(ctx, cmds)
+}
+
+func (s *redisStore) TTL(ctx context.Context, key string) (time.Duration, error) {
+	return pttl(s.client.Do(ctx, s.client.B().Pttl().Key(key).Build()))
+}
+
+func (s *redisStore) TTLs(ctx context.Context, keys []string) (map[string]time.Duration, error) {
+	cmds := make(rueidis.Commands, len(keys))
+	for i, key := range keys {
+		cmds[i] = s.client.B().Pttl().Key(key).Build()
+	}
+
+	ttls := make(map[string]time.Duration, len(keys))
+	for i, resp := range s.client.DoMulti(ctx, cmds...) {
+		ttl, err := pttl(resp)
+		if err != nil {
+			if rueidis.IsRedisNil(err) {
+				continue
+			}
+			return nil, err
+		}
+		ttls[keys[i]] = ttl
+	}
+
+	return ttls, nil
+}
+
+// pttl converts a PTTL reply into a TTL, reporting a missing key with rueidis.Nil.
+func pttl(resp rueidis.RedisResult) (time.Duration, error) {
+	ms, err := resp.AsInt64()
+	if err != nil {
+		return 0, err
+	}
+
+	switch ms {
+	case -2:
+		return 0, rueidis.Nil
+	case -1:
+		return -1, nil
+	}
+
+	return time.Duration(ms) * time.Millisecond, nil
+}
+
+func (s *redisStore) Scan(ctx context.Context, cursor uint64, match string, count int64) ([]string, uint64, error) {
+	cmd := s.client.B().Scan().Cursor(cursor).Match(match)
+	if count > 0 {
+		cmd.Count(count)
+	}
+
+	entry, err := s.client.Do(ctx, cmd.Build()).AsScanEntry()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return entry.Elements, entry.Cursor, nil
+}
+
+func (s *redisStore) CompareAndSwap(ctx context.Context, key string, old, value []byte, args SetArgs) (bool, error) {
+	keepTTL := "0"
+	if args.KeepTTL {
+		keepTTL = "1"
+	}
+
+	swapped, err := compareAndSwapScript.Exec(ctx, s.client, []string{key}, []string{
+		rueidis.BinaryString(old),
+		rueidis.BinaryString(value),
+		strconv.FormatBool(value == nil),
+		strconv.FormatInt(args.TTL.Milliseconds(), 10),
+		keepTTL,
+	}).AsInt64()
+
+	return swapped == 1, err
+}
+
+// sum pipelines single-key commands, so the keys may live in different cluster slots, and adds up their
+// integer replies.
+func (s *redisStore) sum(ctx context.Context, cmds rueidis.Commands) (int64, error) {
+	var total int64
+	for _, resp := range s.client.DoMulti(ctx, cmds...) {
+		n, err := resp.AsInt64()
+		if err != nil {
+			return total, err
+		}
+		total += n
+	}
+
+	return total, nil
+}
+
+// setCommand builds a SET command honoring the resolved SetArgs.
+func setCommand(b rueidis.Builder, key string, value []byte, args SetArgs) rueidis.Completed {
+	builder := b.Set().Key(key).Value(rueidis.BinaryString(value))
+
+	if args.NX {
+		builder.Nx()
+	} else if args.XX {
+		builder.Xx()
+	}
+
+	if args.TTL > 0 {
+		builder.Px(args.TTL)
+	} else if args.KeepTTL {
+		builder.Keepttl()
+	}
+
+	return builder.Build()
+}
@@ -0,0 +1,153 @@
+package rv
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/redis/rueidis"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	metricnoop "go.opentelemetry.io/otel/metric/noop"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/DeltaLaboratory/contrib/rv"
+
+// WithTracerProvider records an OpenTelemetry span for every Value operation, carrying the namespace,
+// operation and result as attributes.
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(r *valueConfig) {
+		r.tracerProvider = provider
+	}
+}
+
+// WithMeterProvider records OpenTelemetry metrics for every Value operation: a latency histogram,
+// a payload size histogram, and hit and miss counters for Get.
+func WithMeterProvider(provider metric.MeterProvider) Option {
+	return func(r *valueConfig) {
+		r.meterProvider = provider
+	}
+}
+
+// telemetry holds the instruments of a Value. Without a tracer provider no spans are started and the context
+// is passed through untouched; without a meter provider metrics are recorded into no-op instruments.
+type telemetry struct {
+	namespace attribute.KeyValue
+	// subNamespace is the full namespace of a Value created with Sub. It is only recorded on spans, as metrics
+	// keep the namespace of the parent to bound their cardinality.
+	subNamespace attribute.KeyValue
+	tracer       trace.Tracer
+	duration     metric.Float64Histogram
+	size         metric.Int64Histogram
+	hits         metric.Int64Counter
+	misses       metric.Int64Counter
+}
+
+func newTelemetry(namespace string, config valueConfig) *telemetry {
+	meterProvider := config.meterProvider
+	if meterProvider == nil {
+		meterProvider = metricnoop.NewMeterProvider()
+	}
+
+	meter := meterProvider.Meter(instrumentationName)
+	t := &telemetry{namespace: attribute.String("rv.namespace", namespace)}
+	if config.tracerProvider != nil {
+		t.tracer = config.tracerProvider.Tracer(instrumentationName)
+	}
+
+	var err error
+	if t.duration, err = meter.Float64Histogram("rv.operation.duration",
+		metric.WithDescription("Duration of rv.Value operations."),
+		metric.WithUnit("s"),
+	); err != nil {
+		otel.Handle(err)
+	}
+	if t.size, err = meter.Int64Histogram("rv.payload.size",
+		metric.WithDescription("Size of encoded payloads read or written by rv.Value operations."),
+		metric.WithUnit("By"),
+	); err != nil {
+		otel.Handle(err)
+	}
+	if t.hits, err = meter.Int64Counter("rv.cache.hits",
+		metric.WithDescription("Number of rv.Value reads that found the key."),
+	); err != nil {
+		otel.Handle(err)
+	}
+	if t.misses, err = meter.Int64Counter("rv.cache.misses",
+		metric.WithDescription("Number of rv.Value reads that did not find the key."),
+	); err != nil {
+		otel.Handle(err)
+	}
+
+	return t
+}
+
+// sub returns the telemetry of a sub-namespace, sharing the instruments and metric attributes of t.
+func (t *telemetry) sub(namespace string) *telemetry {
+	sub := *t
+	sub.subNamespace = attribute.String("rv.subnamespace", namespace)
+
+	return &sub
+}
+
+// operation is an in-progress instrumented Value operation.
+type operation struct {
+	t       *telemetry
+	ctx     context.Context
+	span    trace.Span
+	name    string
+	started time.Time
+}
+
+// start opens a span for the named operation. The returned context carries the span.
+func (t *telemetry) start(ctx context.Context, name string) (context.Context, *operation) {
+	if t.tracer == nil {
+		return ctx, &operation{t: t, ctx: ctx, span: trace.SpanFromContext(context.Background()), name: name, started: time.Now()}
+	}
+
+	attrs := []attribute.KeyValue{t.namespace, attribute.String("rv.operation", name)}
+	if t.subNamespace.Valid() {
+		attrs = append(attrs, t.subNamespace)
+	}
+
+	ctx, span := t.tracer.Start(ctx, "rv."+name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrs...),
+	)
+
+	return ctx, &operation{t: t, ctx: ctx, span: span, name: name, started: time.Now()}
+}
+
+// payload records the size of an encoded payload handled by the operation.
+func (o *operation) payload(size int) {
+	o.span.SetAttributes(attribute.Int("rv.payload.size", size))
+	o.t.size.Record(o.ctx, int64(size), metric.WithAttributes(o.t.namespace, attribute.String("rv.operation", o.name)))
+}
+
+// end records the outcome of the operation and closes its span.
+// Reads report "hit" or "miss"; other operations report "ok", or "miss" when a condition was not met.
+func (o *operation) end(err error) {
+	result := "ok"
+	switch {
+	case err == nil && o.name == "get":
+		result = "hit"
+		o.t.hits.Add(o.ctx, 1, metric.WithAttributes(o.t.namespace))
+	case errors.Is(err, rueidis.Nil):
+		result = "miss"
+		if o.name == "get" {
+			o.t.misses.Add(o.ctx, 1, metric.WithAttributes(o.t.namespace))
+		}
+	case err != nil:
+		result = "error"
+		o.span.RecordError(err)
+		o.span.SetStatus(codes.Error, err.Error())
+	}
+
+	attrs := []attribute.KeyValue{o.t.namespace, attribute.String("rv.operation", o.name), attribute.String("rv.result", result)}
+	o.span.SetAttributes(attrs[2])
+	o.t.duration.Record(o.ctx, time.Since(o.started).Seconds(), metric.WithAttributes(attrs...))
+	o.span.End()
+}
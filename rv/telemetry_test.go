@@ -0,0 +1,152 @@
+package rv
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/redis/rueidis"
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestValueRecordsSpansWithResult(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	value := NewValue[testPayload](nil, nil, "traced", WithStore(NewMemoryStore()), WithTracerProvider(provider))
+
+	if err := value.Set(ctx, "key", &testPayload{Message: "hello"}); err != nil {
+		t.Fatalf("Set returned error: %v", err)
+	}
+	if _, err := value.Get(ctx, "key"); err != nil {
+		t.Fatalf("Get returned error: %v", err)
+	}
+	if _, err := value.Get(ctx, "missing"); !errors.Is(err, rueidis.Nil) {
+		t.Fatalf("expected redis nil error, got %v", err)
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 3 {
+		t.Fatalf("expected 3 spans, got %d", len(spans))
+	}
+
+	expected := []struct {
+		name   string
+		result string
+	}{
+		{"rv.set", "ok"},
+		{"rv.get", "hit"},
+		{"rv.get", "miss"},
+	}
+	for i, span := range spans {
+		if span.Name() != expected[i].name {
+			t.Fatalf("span %d: expected name %q, got %q", i, expected[i].name, span.Name())
+		}
+
+		attrs := attribute.NewSet(span.Attributes()...)
+		if namespace, _ := attrs.Value("rv.namespace"); namespace.AsString() != "traced" {
+			t.Fatalf("span %d: unexpected namespace %q", i, namespace.AsString())
+		}
+		if result, _ := attrs.Value("rv.result"); result.AsString() != expected[i].result {
+			t.Fatalf("span %d: expected result %q, got %q", i, expected[i].result, result.AsString())
+		}
+	}
+}
+
+func TestValueRecordsHitAndMissCounters(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	reader := sdkmetric.NewManualReader()
+	provider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+
+	value := NewValue[testPayload](nil, nil, "metered", WithStore(NewMemoryStore()), WithMeterProvider(provider))
+
+	if err := value.Set(ctx, "key", &testPayload{Message: "hello"}); err != nil {
+		t.Fatalf("Set returned error: %v", err)
+	}
+	_, _ = value.Get(ctx, "key")
+	_, _ = value.Get(ctx, "key")
+	_, _ = value.Get(ctx, "missing")
+
+	var data metricdata.ResourceMetrics
+	if err := reader.Collect(ctx, &data); err != nil {
+		t.Fatalf("Collect returned error: %v", err)
+	}
+
+	counts := map[string]int64{}
+	var sawDuration bool
+	for _, scope := range data.ScopeMetrics {
+		for _, m := range scope.Metrics {
+			switch agg := m.Data.(type) {
+			case metricdata.Sum[int64]:
+				for _, point := range agg.DataPoints {
+					counts[m.Name] += point.Value
+				}
+			case metricdata.Histogram[float64]:
+				sawDuration = sawDuration || m.Name == "rv.operation.duration"
+			}
+		}
+	}
+
+	if counts["rv.cache.hits"] != 2 || counts["rv.cache.misses"] != 1 {
+		t.Fatalf("unexpected hit/miss counts %v", counts)
+	}
+	if !sawDuration {
+		t.Fatalf("expected rv.operation.duration to be recorded")
+	}
+}
+
+func TestSubKeepsParentMetricNamespace(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	recorder := tracetest.NewSpanRecorder()
+	tracerProvider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	reader := sdkmetric.NewManualReader()
+	meterProvider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+
+	value := NewValue[testPayload](nil, nil, "carts", WithStore(NewMemoryStore()),
+		WithTracerProvider(tracerProvider), WithMeterProvider(meterProvider))
+
+	for _, user := range []string{"user-1", "user-2"} {
+		if _, err := value.Sub(user).Get(ctx, "items"); !errors.Is(err, rueidis.Nil) {
+			t.Fatalf("expected redis nil error, got %v", err)
+		}
+	}
+
+	var data metricdata.ResourceMetrics
+	if err := reader.Collect(ctx, &data); err != nil {
+		t.Fatalf("Collect returned error: %v", err)
+	}
+	for _, scope := range data.ScopeMetrics {
+		for _, m := range scope.Metrics {
+			misses, ok := m.Data.(metricdata.Sum[int64])
+			if !ok || m.Name != "rv.cache.misses" {
+				continue
+			}
+			if len(misses.DataPoints) != 1 {
+				t.Fatalf("expected one series for both sub-namespaces, got %d", len(misses.DataPoints))
+			}
+			if namespace, _ := misses.DataPoints[0].Attributes.Value("rv.namespace"); namespace.AsString() != "carts" {
+				t.Fatalf("unexpected metric namespace %q", namespace.AsString())
+			}
+		}
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+	attrs := attribute.NewSet(spans[1].Attributes()...)
+	if sub, _ := attrs.Value("rv.subnamespace"); sub.AsString() != "carts:user-2" {
+		t.Fatalf("unexpected span sub-namespace %q", sub.AsString())
+	}
+}
@@ -0,0 +1,191 @@
+package rv
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/redis/rueidis"
+)
+
+var (
+	// ErrTxConflict is returned by RunTx when a key read with WatchGet changed before the transaction executed.
+	ErrTxConflict = errors.New("transaction aborted: watched key changed")
+	// ErrTxPending is returned by TxResult.Value before the transaction has executed.
+	ErrTxPending = errors.New("transaction has not executed yet")
+	// ErrTxConditionalSet is returned by TxSet for SetNX and SetXX, whose skipped writes a transaction cannot
+	// report. Guard the write with WatchGet instead.
+	ErrTxConditionalSet = errors.New("conditional set is not supported in a transaction")
+)
+
+// Tx queues operations from several Values, possibly of different types, and executes them atomically with
+// MULTI/EXEC on a dedicated connection. A Tx is only valid inside the RunTx callback that created it.
+type Tx struct {
+	conn     rueidis.DedicatedClient
+	cmds     rueidis.Commands
+	results  []func(msg rueidis.RedisMessage)
+	watching bool
+}
+
+// RunTx runs fn on a dedicated connection of client and then executes the operations fn queued in a single
+// MULTI/EXEC transaction. Values used with the Tx must share client, and on Redis Cluster all keys must hash to
+// the same slot. Reads made through WatchGet are watched, and if any of those keys changes before EXEC the
+// transaction is discarded and ErrTxConflict is returned, so the caller may retry. If fn returns an error,
+// nothing is executed. If a queued operation fails inside EXEC, e.g. with OOM or WRONGTYPE, the others still take
+// effect, as Redis does not roll transactions back, and RunTx returns the first failure.
+func RunTx(ctx context.Context, client rueidis.Client, fn func(ctx context.Context, tx *Tx) error) error {
+	return client.Dedicated(func(conn rueidis.DedicatedClient) error {
+		tx := &Tx{conn: conn}
+
+		if err := fn(ctx, tx); err != nil {
+			tx.unwatch(ctx)
+			return err
+		}
+
+		if len(tx.cmds) == 0 {
+			tx.unwatch(ctx)
+			return nil
+		}
+
+		cmds := make(rueidis.Commands, 0, len(tx.cmds)+2)
+		cmds = append(cmds, conn.B().Multi().Build())
+		cmds = append(cmds, tx.cmds...)
+		cmds = append(cmds, conn.B().Exec().Build())
+
+		resps := conn.DoMulti(ctx, cmds...)
+		for _, resp := range resps[:len(resps)-1] {
+			if err := resp.Error(); err != nil {
+				return fmt.Errorf("failed to queue transaction: %w", err)
+			}
+		}
+
+		exec := resps[len(resps)-1]
+		if rueidis.IsRedisNil(exec.Error()) {
+			return ErrTxConflict
+		}
+
+		replies, err := exec.ToArray()
+		if err != nil {
+			return fmt.Errorf("failed to execute transaction: %w", err)
+		}
+
+		var failed error
+		for i, reply := range replies {
+			if tx.results[i] != nil {
+				tx.results[i](reply)
+			}
+			if err := reply.Error(); err != nil && !rueidis.IsRedisNil(err) && failed == nil {
+				failed = fmt.Errorf("failed to execute transaction command %d: %w", i, err)
+			}
+		}
+
+		return failed
+	})
+}
+
+func (tx *Tx) queue(cmd rueidis.Completed, result func(msg rueidis.RedisMessage)) {
+	tx.cmds = append(tx.cmds, cmd)
+	tx.results = append(tx.results, result)
+}
+
+func (tx *Tx) unwatch(ctx context.Context) {
+	if tx.watching {
+		// The connection goes back to the pool; a failed UNWATCH is cleared by the next EXEC or by reconnection.
+		_ = tx.conn.Do(ctx, tx.conn.B().Unwatch().Build()).Error()
+	}
+}
+
+// TxResult is the typed result of a read queued in a Tx.
+type TxResult[T any] struct {
+	value    *T
+	err      error
+	executed bool
+}
+
+// Value returns the value read by the transaction. Missing keys are reported with an error wrapping rueidis.Nil.
+func (r *TxResult[T]) Value() (*T, error) {
+	if !r.executed {
+		return nil, ErrTxPending
+	}
+
+	return r.value, r.err
+}
+
+// WatchGet watches the key and reads its current value immediately. If the key changes before the
+// transaction executes, RunTx returns ErrTxConflict.
+func (r *Value[T]) WatchGet(ctx context.Context, tx *Tx, key string) (*T, error) {
+	rawKey := r.namespacedKey(key)
+
+	if err := tx.conn.Do(ctx, tx.conn.B().Watch().Key(rawKey).Build()).Error(); err != nil {
+		return nil, fmt.Errorf("failed to watch key: %w", err)
+	}
+	tx.watching = true
+
+	resp, err := tx.conn.Do(ctx, tx.conn.B().Get().Key(rawKey).Build()).AsBytes()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get value: %w", err)
+	}
+
+	return r.decodeValue(resp)
+}
+
+// TxGet queues a read of the key. Its result is available once RunTx returns.
+func (r *Value[T]) TxGet(tx *Tx, key string) *TxResult[T] {
+	result := &TxResult[T]{}
+
+	tx.queue(tx.conn.B().Get().Key(r.namespacedKey(key)).Build(), func(msg rueidis.RedisMessage) {
+		result.executed = true
+
+		data, err := msg.AsBytes()
+		if err != nil {
+			result.err = fmt.Errorf("failed to get value: %w", err)
+			return
+		}
+
+		result.value, result.err = r.decodeValue(data)
+	})
+
+	return result
+}
+
+// TxSet queues storing the value under the key, honoring the same options and payload limit as Set, except for
+// SetNX and SetXX, which return ErrTxConditionalSet. A transaction cannot replace chunks, so it returns
+// ErrChunkingUnsupported if the Value uses WithChunking.
+func (r *Value[T]) TxSet(tx *Tx, key string, value *T, setOptions ...SetOption) error {
+	if r.config.chunkSize > 0 {
+		return ErrChunkingUnsupported
+	}
+
+	encoded, err := r.encodeEntry(value, recomputeDelta(setOptions))
+	if err != nil {
+		return err
+	}
+
+	if err := r.checkPayload(key, encoded); err != nil {
+		return err
+	}
+
+	args, err := r.config.setArgs(setOptions)
+	if err != nil {
+		return err
+	}
+	if args.NX || args.XX {
+		return ErrTxConditionalSet
+	}
+
+	tx.queue(setCommand(tx.conn.B(), r.namespacedKey(key), encoded, args), nil)
+
+	return nil
+}
+
+// TxDelete queues removing the key. A transaction cannot remove chunks, so it returns ErrChunkingUnsupported if
+// the Value uses WithChunking.
+func (r *Value[T]) TxDelete(tx *Tx, key string) error {
+	if r.config.chunkSize > 0 {
+		return ErrChunkingUnsupported
+	}
+
+	tx.queue(tx.conn.B().Del().Key(r.namespacedKey(key)).Build(), nil)
+
+	return nil
+}
@@ -0,0 +1,213 @@
+package rv
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/redis/rueidis"
+	rueidismock "github.com/redis/rueidis/mock"
+	"go.uber.org/mock/gomock"
+)
+
+type testIndex struct {
+	OrderID string
+}
+
+func TestRunTxExecutesQueuedOperationsAtomically(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	client := rueidismock.NewClient(ctrl)
+	conn := rueidismock.NewDedicatedClient(ctrl)
+	orders := NewValue[testPayload](client, nil, "orders")
+	index := NewValue[testIndex](client, nil, "index")
+
+	order := testPayload{Message: "order-1"}
+	client.EXPECT().
+		Dedicated(gomock.Any()).
+		DoAndReturn(func(fn func(rueidis.DedicatedClient) error) error { return fn(conn) })
+	conn.EXPECT().
+		DoMulti(ctx,
+			rueidismock.Match("MULTI"),
+			rueidismock.Match("SET", "orders:1", string(mustEncode(order))),
+			rueidismock.Match("SET", "index:customer:7", string(mustMarshal(t, testIndex{OrderID: "1"}))),
+			rueidismock.Match("DEL", "index:pending:1"),
+			rueidismock.Match("GET", "orders:1"),
+			rueidismock.Match("EXEC"),
+		).
+		Return([]rueidis.RedisResult{
+			rueidismock.Result(rueidismock.RedisString("OK")),
+			rueidismock.Result(rueidismock.RedisString("QUEUED")),
+			rueidismock.Result(rueidismock.RedisString("QUEUED")),
+			rueidismock.Result(rueidismock.RedisString("QUEUED")),
+			rueidismock.Result(rueidismock.RedisString("QUEUED")),
+			rueidismock.Result(rueidismock.RedisArray(
+				rueidismock.RedisString("OK"),
+				rueidismock.RedisString("OK"),
+				rueidismock.RedisInt64(1),
+				rueidismock.RedisBlobString(string(mustEncode(order))),
+			)),
+		})
+
+	var stored *TxResult[testPayload]
+	err := RunTx(ctx, client, func(ctx context.Context, tx *Tx) error {
+		if err := orders.TxSet(tx, "1", &order); err != nil {
+			return err
+		}
+		if err := index.TxSet(tx, "customer:7", &testIndex{OrderID: "1"}); err != nil {
+			return err
+		}
+		if err := index.TxDelete(tx, "pending:1"); err != nil {
+			return err
+		}
+		stored = orders.TxGet(tx, "1")
+
+		if _, err := stored.Value(); !errors.Is(err, ErrTxPending) {
+			t.Fatalf("expected ErrTxPending before execution, got %v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("RunTx returned error: %v", err)
+	}
+
+	result, err := stored.Value()
+	if err != nil {
+		t.Fatalf("Value returned error: %v", err)
+	}
+	if result.Message != "order-1" {
+		t.Fatalf("unexpected result %+v", result)
+	}
+}
+
+func TestRunTxReportsWatchConflict(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	client := rueidismock.NewClient(ctrl)
+	conn := rueidismock.NewDedicatedClient(ctrl)
+	orders := NewValue[testPayload](client, nil, "orders")
+
+	current := testPayload{Message: "v1"}
+	next := testPayload{Message: "v2"}
+
+	client.EXPECT().
+		Dedicated(gomock.Any()).
+		DoAndReturn(func(fn func(rueidis.DedicatedClient) error) error { return fn(conn) })
+	gomock.InOrder(
+		conn.EXPECT().
+			Do(ctx, rueidismock.Match("WATCH", "orders:1")).
+			Return(rueidismock.Result(rueidismock.RedisString("OK"))),
+		conn.EXPECT().
+			Do(ctx, rueidismock.Match("GET", "orders:1")).
+			Return(rueidismock.Result(rueidismock.RedisBlobString(string(mustEncode(current))))),
+		conn.EXPECT().
+			DoMulti(ctx,
+				rueidismock.Match("MULTI"),
+				rueidismock.Match("SET", "orders:1", string(mustEncode(next))),
+				rueidismock.Match("EXEC"),
+			).
+			Return([]rueidis.RedisResult{
+				rueidismock.Result(rueidismock.RedisString("OK")),
+				rueidismock.Result(rueidismock.RedisString("QUEUED")),
+				rueidismock.Result(rueidismock.RedisNil()),
+			}),
+	)
+
+	err := RunTx(ctx, client, func(ctx context.Context, tx *Tx) error {
+		value, err := orders.WatchGet(ctx, tx, "1")
+		if err != nil {
+			return err
+		}
+		if value.Message != "v1" {
+			t.Fatalf("unexpected watched value %+v", value)
+		}
+		return orders.TxSet(tx, "1", &next)
+	})
+	if !errors.Is(err, ErrTxConflict) {
+		t.Fatalf("expected ErrTxConflict, got %v", err)
+	}
+}
+
+func TestTxSetEnforcesValueLimits(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	client := rueidismock.NewClient(ctrl)
+	conn := rueidismock.NewDedicatedClient(ctrl)
+	limited := NewValue[testPayload](client, nil, "limited", WithMaxPayloadSize(8))
+	chunked := NewValue[testPayload](client, nil, "chunked", WithChunking(8))
+	plain := NewValue[testPayload](client, nil, "plain")
+
+	client.EXPECT().
+		Dedicated(gomock.Any()).
+		DoAndReturn(func(fn func(rueidis.DedicatedClient) error) error { return fn(conn) })
+
+	err := RunTx(ctx, client, func(ctx context.Context, tx *Tx) error {
+		var tooLarge *PayloadTooLargeError
+		if err := limited.TxSet(tx, "1", &testPayload{Message: "larger than the limit"}); !errors.As(err, &tooLarge) {
+			t.Fatalf("expected *PayloadTooLargeError, got %v", err)
+		}
+		if err := chunked.TxSet(tx, "1", &testPayload{Message: "small"}); !errors.Is(err, ErrChunkingUnsupported) {
+			t.Fatalf("expected ErrChunkingUnsupported, got %v", err)
+		}
+		if err := plain.TxSet(tx, "1", &testPayload{Message: "small"}, SetNX(true)); !errors.Is(err, ErrTxConditionalSet) {
+			t.Fatalf("expected ErrTxConditionalSet, got %v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("RunTx returned error: %v", err)
+	}
+}
+
+func TestRunTxReportsFailedCommand(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	client := rueidismock.NewClient(ctrl)
+	conn := rueidismock.NewDedicatedClient(ctrl)
+	orders := NewValue[testPayload](client, nil, "orders")
+
+	client.EXPECT().
+		Dedicated(gomock.Any()).
+		DoAndReturn(func(fn func(rueidis.DedicatedClient) error) error { return fn(conn) })
+	conn.EXPECT().
+		DoMulti(ctx, gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+		Return([]rueidis.RedisResult{
+			rueidismock.Result(rueidismock.RedisString("OK")),
+			rueidismock.Result(rueidismock.RedisString("QUEUED")),
+			rueidismock.Result(rueidismock.RedisString("QUEUED")),
+			rueidismock.Result(rueidismock.RedisArray(
+				rueidismock.RedisNil(),
+				rueidismock.RedisError("OOM command not allowed when used memory > 'maxmemory'"),
+			)),
+		})
+
+	var missing *TxResult[testPayload]
+	err := RunTx(ctx, client, func(ctx context.Context, tx *Tx) error {
+		missing = orders.TxGet(tx, "1")
+		return orders.TxSet(tx, "2", &testPayload{Message: "order-2"})
+	})
+
+	var redisErr *rueidis.RedisError
+	if !errors.As(err, &redisErr) {
+		t.Fatalf("expected the OOM error, got %v", err)
+	}
+	if _, err := missing.Value(); !errors.Is(err, rueidis.Nil) {
+		t.Fatalf("expected redis nil error for the missing key, got %v", err)
+	}
+}
@@ -7,27 +7,50 @@ import (
 	"strings"
 	"time"
 
-	"github.com/fxamacker/cbor/v2"
 	"github.com/redis/rueidis"
 	"github.com/redis/rueidis/rueidislock"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // Value is a typed wrapper around a namespaced Redis keyspace backed by rueidis.
+// The storage can be swapped with WithStore, e.g. for a MemoryStore in tests.
 type Value[T any] struct {
 	client rueidis.Client
+	store  Store
 	locker rueidislock.Locker
 	key    string
 
-	config valueConfig
+	config    valueConfig
+	telemetry *telemetry
 }
 
 type valueConfig struct {
-	expires *time.Duration
+	expires          *time.Duration
+	hashTag          bool
+	maxPayloadSize   int
+	chunkSize        int
+	breakerThreshold int
+	breakerCooldown  time.Duration
+	earlyExpiration  float64
+	ttlJitter        float64
+	lockTimeout      time.Duration
+	lockHolder       string
+	lockKeyPrefix    string
+	lockKeyMajority  int
+	recordLockHolder bool
+	jsonFallback     bool
+	store            Store
+	tracerProvider   trace.TracerProvider
+	meterProvider    metric.MeterProvider
+	schemaVersion    *uint64
+	upgrades         map[uint64]schemaUpgrade
 }
 
 type Option func(*valueConfig)
 
 // NewValue instantiates a Value helper for the provided key prefix and client options.
+// It panics if an Upgrade option does not convert into T, as that is a programming error.
 func NewValue[T any](client rueidis.Client, locker rueidislock.Locker, key string, options ...Option) *Value[T] {
 	r := &Value[T]{key: key, client: client, locker: locker}
 
@@ -37,9 +60,35 @@ func NewValue[T any](client rueidis.Client, locker rueidislock.Locker, key strin
 		}
 	}
 
+	if err := checkUpgrades[T](r.config.upgrades); err != nil {
+		panic("rv: " + err.Error())
+	}
+
+	if r.config.hashTag {
+		r.key = "{" + key + "}"
+	}
+
+	r.store = r.config.newStore(client)
+
+	r.telemetry = newTelemetry(key, r.config)
+
 	return r
 }
 
+// newStore returns the Store configured with WithStore, or one backed by client, guarded by the circuit breaker
+// configured with WithCircuitBreaker.
+func (c *valueConfig) newStore(client rueidis.Client) Store {
+	store := c.store
+	if store == nil {
+		store = NewRedisStore(client)
+	}
+	if c.breakerThreshold > 0 {
+		store = newBreakerStore(store, c.breakerThreshold, c.breakerCooldown)
+	}
+
+	return store
+}
+
 // WithDefaultExpiration configures a default TTL applied to every Set call.
 func WithDefaultExpiration(duration time.Duration) Option {
 	return func(r *valueConfig) {
@@ -50,6 +99,10 @@ func WithDefaultExpiration(duration time.Duration) Option {
 type setOption struct {
 	TTL     *time.Duration
 	KeepTTL *bool
+	NX      *bool
+	XX      *bool
+	Delta   *time.Duration
+	Jitter  *float64
 }
 
 type SetOption func(*setOption)
@@ -66,27 +119,56 @@ func SetKeepTTL(keep bool) SetOption {
 	}
 }
 
-// WithLock acquires a distributed lock for the given key and executes the provided function within the lock's context.
-// It does not mean that the key itself is locked, but rather a namespaced lock based on the provided key.
-func (r *Value[T]) WithLock(ctx context.Context, key string, fn func(ctx context.Context) error) error {
-	ctx, release, err := r.locker.WithContext(ctx, r.key+":"+key)
-	if err != nil {
-		return fmt.Errorf("failed to acquire lock: %w", err)
+// SetNX only stores the value if the key does not exist yet.
+// When the key already exists, Set returns an error wrapping rueidis.Nil.
+func SetNX(nx bool) SetOption {
+	return func(o *setOption) {
+		o.NX = &nx
 	}
-	defer release()
+}
 
-	return fn(ctx)
+// SetXX only stores the value if the key already exists.
+// When the key does not exist, Set returns an error wrapping rueidis.Nil.
+func SetXX(xx bool) SetOption {
+	return func(o *setOption) {
+		o.XX = &xx
+	}
 }
 
 // Set encodes and stores the provided value under the namespaced key.
-func (r *Value[T]) Set(ctx context.Context, key string, value *T, setOptions ...SetOption) error {
-	encoded, err := cbor.Marshal(value)
+func (r *Value[T]) Set(ctx context.Context, key string, value *T, setOptions ...SetOption) (err error) {
+	ctx, op := r.telemetry.start(ctx, "set")
+	defer func() { op.end(err) }()
+
+	encoded, err := r.encodeEntry(value, recomputeDelta(setOptions))
+	if err != nil {
+		return err
+	}
+	op.payload(len(encoded))
+
+	if err := r.checkPayload(key, encoded); err != nil {
+		return err
+	}
+
+	args, err := r.config.setArgs(setOptions)
+	if err != nil {
+		return err
+	}
+
+	if r.config.chunkSize > 0 {
+		err = r.setChunked(ctx, r.namespacedKey(key), encoded, args)
+	} else {
+		err = r.store.Set(ctx, r.namespacedKey(key), encoded, args)
+	}
 	if err != nil {
-		return fmt.Errorf("failed to encode value: %w", err)
+		return fmt.Errorf("failed to set value: %w", err)
 	}
 
-	builder := r.client.B().Set().Key(r.key + ":" + key).Value(rueidis.BinaryString(encoded))
+	return nil
+}
 
+// setArgs resolves per-call SetOptions against the configured defaults.
+func (c *valueConfig) setArgs(setOptions []SetOption) (SetArgs, error) {
 	var options setOption
 	for _, opt := range setOptions {
 		opt(&options)
@@ -94,41 +176,83 @@ func (r *Value[T]) Set(ctx context.Context, key string, value *T, setOptions ...
 
 	hasTTL := options.TTL != nil
 	hasKeepTTL := options.KeepTTL != nil && *options.KeepTTL
-	hasDefaultTTL := r.config.expires != nil
+	hasDefaultTTL := c.expires != nil
 
 	if hasTTL && hasKeepTTL {
-		return errors.New("cannot use SetTTL and SetKeepTTL simultaneously")
+		return SetArgs{}, errors.New("cannot use SetTTL and SetKeepTTL simultaneously")
+	}
+
+	args := SetArgs{
+		NX: options.NX != nil && *options.NX,
+		XX: options.XX != nil && *options.XX,
+	}
+
+	if args.NX && args.XX {
+		return SetArgs{}, errors.New("cannot use SetNX and SetXX simultaneously")
 	}
 
 	if hasTTL {
-		builder.Ex(*options.TTL)
+		args.TTL = *options.TTL
 	} else if hasKeepTTL {
-		builder.Keepttl()
+		args.KeepTTL = true
 	} else if hasDefaultTTL {
-		builder.Ex(*r.config.expires)
+		args.TTL = *c.expires
 	}
 
-	err = r.client.Do(ctx, builder.Build()).Error()
-	if err != nil {
-		return fmt.Errorf("failed to set value: %w", err)
+	jitter := c.ttlJitter
+	if options.Jitter != nil {
+		jitter = *options.Jitter
 	}
+	args.TTL = jitterTTL(args.TTL, jitter)
 
-	return nil
+	return args, nil
 }
 
 // Get loads a value by key.
-func (r *Value[T]) Get(ctx context.Context, key string) (*T, error) {
-	resp, err := r.client.Do(ctx, r.client.B().Get().Key(r.key+":"+key).Build()).AsBytes()
+func (r *Value[T]) Get(ctx context.Context, key string) (_ *T, err error) {
+	ctx, op := r.telemetry.start(ctx, "get")
+	defer func() { op.end(err) }()
+
+	// Early expiration needs the TTL, which is read along with the value rather than in a second round trip.
+	var (
+		resp []byte
+		ttl  time.Duration
+	)
+	if r.config.earlyExpiration > 0 {
+		resp, ttl, err = r.store.GetTTL(ctx, r.namespacedKey(key))
+	} else {
+		resp, err = r.store.Get(ctx, r.namespacedKey(key))
+	}
+	if err == nil {
+		resp, err = r.resolveChunks(ctx, r.namespacedKey(key), resp)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get value: %w", err)
 	}
+	op.payload(len(resp))
+
+	value, delta, err := r.decodeEntry(resp)
+	if err != nil {
+		return nil, err
+	}
+
+	if r.config.earlyExpiration > 0 && delta > 0 && r.expiresEarly(ctx, r.namespacedKey(key), delta, ttl) {
+		return nil, fmt.Errorf("failed to get value: %w", ErrEarlyExpiration)
+	}
 
-	return r.decodeValue(resp)
+	return value, nil
 }
 
 // Delete removes the namespaced key from Redis.
-func (r *Value[T]) Delete(ctx context.Context, key string) error {
-	err := r.client.Do(ctx, r.client.B().Del().Key(r.key+":"+key).Build()).Error()
+func (r *Value[T]) Delete(ctx context.Context, key string) (err error) {
+	ctx, op := r.telemetry.start(ctx, "delete")
+	defer func() { op.end(err) }()
+
+	if r.config.chunkSize > 0 {
+		err = r.deleteChunked(ctx, r.namespacedKey(key))
+	} else {
+		_, err = r.store.Delete(ctx, r.namespacedKey(key))
+	}
 	if err != nil {
 		return fmt.Errorf("failed to delete value: %w", err)
 	}
@@ -136,73 +260,124 @@ func (r *Value[T]) Delete(ctx context.Context, key string) error {
 	return nil
 }
 
+// Expire sets the TTL of the namespaced key without rewriting its value, so concurrent updates are kept.
+// A missing key is reported with an error wrapping rueidis.Nil.
+func (r *Value[T]) Expire(ctx context.Context, key string, ttl time.Duration) (err error) {
+	ctx, op := r.telemetry.start(ctx, "expire")
+	defer func() { op.end(err) }()
+
+	keys := []string{r.namespacedKey(key)}
+	if r.config.chunkSize > 0 {
+		data, err := r.store.Get(ctx, keys[0])
+		if err != nil {
+			return fmt.Errorf("failed to expire value: %w", err)
+		}
+		keys = append(keys, r.chunkKeys(keys[0], data)...)
+	}
+
+	updated, err := r.store.Expire(ctx, ttl, keys...)
+	if err == nil && updated == 0 {
+		err = rueidis.Nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to expire value: %w", err)
+	}
+
+	return nil
+}
+
 // Scan iterates through the namespaced keys that match the provided pattern (without the namespace prefix)
 // and returns the decoded values. Passing an empty pattern matches all keys in the namespace.
-func (r *Value[T]) Scan(ctx context.Context, pattern string) ([]*T, error) {
-	match := r.key + ":"
-	if pattern == "" {
-		match += "*"
-	} else {
-		match += pattern
-	}
+func (r *Value[T]) Scan(ctx context.Context, pattern string) (_ []*T, err error) {
+	ctx, op := r.telemetry.start(ctx, "scan")
+	defer func() { op.end(err) }()
 
 	var (
-		cursor uint64
 		values []*T
+		size   int
 	)
 
-	for {
-		entry, err := r.client.Do(ctx, r.client.B().Scan().Cursor(cursor).Match(match).Build()).AsScanEntry()
+	err = r.scanKeys(ctx, pattern, 0, func(keys []string) error {
+		batch, err := r.store.MGet(ctx, keys)
 		if err != nil {
-			return nil, fmt.Errorf("failed to scan values matching %q: %w", pattern, err)
+			return fmt.Errorf("failed to fetch scan batch for %q: %w", pattern, err)
 		}
 
-		if len(entry.Elements) > 0 {
-			batch, err := rueidis.MGet(r.client, ctx, entry.Elements)
-			if err != nil {
-				return nil, fmt.Errorf("failed to fetch scan batch for %q: %w", pattern, err)
-			}
+		return r.decodeBatch(ctx, keys, batch, func(_ int, value *T, n int) {
+			values = append(values, value)
+			size += n
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
 
-			for _, rawKey := range entry.Elements {
-				relativeKey := strings.TrimPrefix(rawKey, r.key+":")
+	op.payload(size)
 
-				msg, ok := batch[rawKey]
-				if !ok {
-					continue
-				}
+	return values, nil
+}
 
-				data, err := msg.AsBytes()
-				if err != nil {
-					if errors.Is(err, rueidis.Nil) {
-						continue // key disappeared between SCAN and MGET
-					}
-					return nil, fmt.Errorf("failed to load key %q: %w", relativeKey, err)
-				}
+// decodeBatch decodes the raw values in batch and calls fn for every key in keys that holds a value, with its
+// index in keys and its encoded size. Keys that are missing, hold bookkeeping of another value, lost their
+// chunks or hold a discarded schema version are skipped, as if they had expired.
+func (r *Value[T]) decodeBatch(ctx context.Context, keys []string, batch map[string][]byte, fn func(i int, value *T, size int)) error {
+	for i, rawKey := range keys {
+		relativeKey := strings.TrimPrefix(rawKey, r.namespacedKey(""))
 
-				value, err := r.decodeValue(data)
-				if err != nil {
-					return nil, fmt.Errorf("failed to decode key %q: %w", relativeKey, err)
-				}
+		data, ok := batch[rawKey]
+		if !ok || isInternal(data) {
+			continue // key disappeared or never existed, or is bookkeeping of another value
+		}
 
-				values = append(values, value)
+		data, err := r.resolveChunks(ctx, rawKey, data)
+		if err != nil {
+			if errors.Is(err, rueidis.Nil) {
+				continue // chunks expired or replaced
 			}
+			return fmt.Errorf("failed to read chunks of key %q: %w", relativeKey, err)
 		}
 
-		if entry.Cursor == 0 {
-			break
+		value, err := r.decodeValue(data)
+		if err != nil {
+			if errors.Is(err, rueidis.Nil) {
+				continue // discarded schema version
+			}
+			return fmt.Errorf("failed to decode key %q: %w", relativeKey, err)
 		}
 
-		cursor = entry.Cursor
+		fn(i, value, len(data))
 	}
 
-	return values, nil
+	return nil
 }
 
-// decodeValue transforms the CBOR payload into the generic type.
-func (r *Value[T]) decodeValue(data []byte) (*T, error) {
-	var value T
-	if err := cbor.Unmarshal(data, &value); err != nil {
-		return nil, fmt.Errorf("failed to decode value: %w", err)
+// scanKeys walks the namespaced keys matching the pattern (without the namespace prefix) and calls fn with
+// every non-empty page of raw keys. Passing an empty pattern matches all keys in the namespace.
+func (r *Value[T]) scanKeys(ctx context.Context, pattern string, count int64, fn func(keys []string) error) error {
+	match := r.namespacedKey("")
+	if pattern == "" {
+		match += "*"
+	} else {
+		match += pattern
+	}
+
+	var cursor uint64
+	for {
+		keys, next, err := r.store.Scan(ctx, cursor, match, count)
+		if err != nil {
+			return fmt.Errorf("failed to scan values matching %q: %w", pattern, err)
+		}
+
+		if len(keys) > 0 {
+			if err := fn(keys); err != nil {
+				return err
+			}
+		}
+
+		if next == 0 {
+			return nil
+		}
+
+		cursor = next
 	}
-	return &value, nil
 }
@@ -30,7 +30,7 @@ func TestValueSetAppliesDefaultExpiration(t *testing.T) {
 
 	client.EXPECT().
 		Do(ctx, matchSetCommand("default:key", func(tokens []string) bool {
-			return hasTokenSequence(tokens, "EX", secondsString(45*time.Second)) &&
+			return hasTokenSequence(tokens, "PX", millisecondsString(45*time.Second)) &&
 				!containsToken(tokens, "KEEPTTL")
 		})).
 		Return(rueidismock.Result(rueidismock.RedisString("OK")))
@@ -51,11 +51,11 @@ func TestValueSetPrefersPerCallTTL(t *testing.T) {
 	client := rueidismock.NewClient(ctrl)
 	value := NewValue[testPayload](client, nil, "percall", WithDefaultExpiration(2*time.Minute))
 
-	perCallTTL := 15 * time.Second
+	perCallTTL := 1500 * time.Millisecond
 	client.EXPECT().
 		Do(ctx, matchSetCommand("percall:item", func(tokens []string) bool {
-			return hasTokenSequence(tokens, "EX", secondsString(perCallTTL)) &&
-				countToken(tokens, "EX") == 1 &&
+			return hasTokenSequence(tokens, "PX", millisecondsString(perCallTTL)) &&
+				countToken(tokens, "PX") == 1 &&
 				!containsToken(tokens, "KEEPTTL")
 		})).
 		Return(rueidismock.Result(rueidismock.RedisString("OK")))
@@ -79,7 +79,7 @@ func TestValueSetKeepsExistingTTLWhenRequested(t *testing.T) {
 	client.EXPECT().
 		Do(ctx, matchSetCommand("keep:session", func(tokens []string) bool {
 			return containsToken(tokens, "KEEPTTL") &&
-				!containsToken(tokens, "EX")
+				!containsToken(tokens, "PX")
 		})).
 		Return(rueidismock.Result(rueidismock.RedisString("OK")))
 
@@ -110,6 +110,50 @@ func TestValueSetRejectsConflictingTTLRequests(t *testing.T) {
 	}
 }
 
+func TestValueSetOnlyIfAbsent(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	client := rueidismock.NewClient(ctrl)
+	value := NewValue[testPayload](client, nil, "nx")
+
+	client.EXPECT().
+		Do(ctx, matchSetCommand("nx:key", func(tokens []string) bool {
+			return containsToken(tokens, "NX") && !containsToken(tokens, "XX")
+		})).
+		Return(rueidismock.Result(rueidismock.RedisNil()))
+
+	payload := testPayload{Message: "first"}
+	err := value.Set(ctx, "key", &payload, SetNX(true))
+	if !errors.Is(err, rueidis.Nil) {
+		t.Fatalf("expected redis nil error when the key exists, got %v", err)
+	}
+}
+
+func TestValueSetRejectsConflictingConditions(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	client := rueidismock.NewClient(ctrl)
+	value := NewValue[testPayload](client, nil, "conflict")
+
+	payload := testPayload{Message: "oops"}
+	err := value.Set(ctx, "key", &payload, SetNX(true), SetXX(true))
+	if err == nil {
+		t.Fatalf("expected error but got nil")
+	}
+	expected := "cannot use SetNX and SetXX simultaneously"
+	if err.Error() != expected {
+		t.Fatalf("expected error %q, got %q", expected, err.Error())
+	}
+}
+
 func TestValueSetEncodesPayload(t *testing.T) {
 	t.Parallel()
 
@@ -127,7 +171,7 @@ func TestValueSetEncodesPayload(t *testing.T) {
 		Do(ctx, matchSetCommand("data:record", func(tokens []string) bool {
 			return len(tokens) >= 3 &&
 				tokens[2] == encoded &&
-				!containsToken(tokens, "EX") &&
+				!containsToken(tokens, "PX") &&
 				!containsToken(tokens, "KEEPTTL")
 		})).
 		Return(rueidismock.Result(rueidismock.RedisString("OK")))
@@ -311,8 +355,8 @@ func countToken(tokens []string, needle string) int {
 	return count
 }
 
-func secondsString(d time.Duration) string {
-	return strconv.FormatInt(int64(d/time.Second), 10)
+func millisecondsString(d time.Duration) string {
+	return strconv.FormatInt(d.Milliseconds(), 10)
 }
 
 func scanResponse(cursor uint64, keys []string) rueidis.RedisMessage {
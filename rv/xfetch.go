@@ -0,0 +1,104 @@
+package rv
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"time"
+
+	"github.com/redis/rueidis"
+)
+
+// ErrEarlyExpiration is reported by Get, wrapped, when the caller was chosen to refresh a value ahead of its
+// expiration. It wraps rueidis.Nil, so the caller handles it like any other miss.
+var ErrEarlyExpiration = fmt.Errorf("value expires early for refresh: %w", rueidis.Nil)
+
+// refreshClaim marks the claim of an early refresh: CBOR tag 0x7279 wrapping null, recognized by refreshPrefix.
+var (
+	refreshPrefix = []byte{0xd9, 0x72, 0x79}
+	refreshClaim  = []byte{0xd9, 0x72, 0x79, 0xf6}
+)
+
+// WithEarlyExpiration makes Get treat values as expired slightly before their TTL, using the XFetch
+// algorithm: the closer a value is to expiring and the longer it took to compute, the likelier a read reports
+// ErrEarlyExpiration, so values written at the same moment are refreshed at different moments instead of all
+// missing together. Only one caller at a time is told to refresh a key; everyone else keeps reading the cached
+// value until it is replaced. Beta scales how early values expire; 1 is the recommended default.
+//
+// Values need a recorded computation time, written by Fetch or by Set with SetDelta. Values without one, or
+// without a TTL, only expire normally.
+func WithEarlyExpiration(beta float64) Option {
+	return func(r *valueConfig) {
+		r.earlyExpiration = beta
+	}
+}
+
+// SetDelta records how long computing the value took, which WithEarlyExpiration uses to expire it early.
+func SetDelta(delta time.Duration) SetOption {
+	return func(o *setOption) {
+		o.Delta = &delta
+	}
+}
+
+// Fetch returns the value of key, computing and caching it with recompute on a miss. The computation time is
+// recorded with the value, so together with WithEarlyExpiration values are refreshed before they expire. If
+// caching the computed value fails, Fetch returns the value together with the error.
+func (r *Value[T]) Fetch(ctx context.Context, key string, recompute func(ctx context.Context) (*T, error), setOptions ...SetOption) (*T, error) {
+	value, err := r.Get(ctx, key)
+	if err == nil || !errors.Is(err, rueidis.Nil) {
+		return value, err
+	}
+
+	started := time.Now()
+	value, err = recompute(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.Set(ctx, key, value, append(setOptions[:len(setOptions):len(setOptions)], SetDelta(time.Since(started)))...); err != nil {
+		return value, err
+	}
+
+	return value, nil
+}
+
+// expiresEarly reports whether the caller should refresh the value under rawKey, which expires after ttl, ahead
+// of its expiration, and claims the refresh if so.
+func (r *Value[T]) expiresEarly(ctx context.Context, rawKey string, delta, ttl time.Duration) bool {
+	if ttl <= 0 {
+		return false
+	}
+
+	// XFetch: expire once delta * beta * -ln(rand) reaches the remaining TTL. 1-rand avoids ln(0).
+	gap := time.Duration(float64(delta) * r.config.earlyExpiration * -math.Log(1-rand.Float64()))
+	if gap < ttl {
+		return false
+	}
+
+	// The claim lasts as long as the value, so only one caller refreshes it.
+	err := r.store.Set(ctx, r.internalKey("refresh", rawKey), refreshClaim, SetArgs{TTL: ttl, NX: true})
+	return err == nil
+}
+
+// isInternal reports whether data is bookkeeping of a value, such as a chunk, a refresh claim or a lock holder
+// record, rather than a value itself. Bookkeeping lives outside the namespace of its Value, but inside that of a
+// parent Value of a Sub.
+func isInternal(data []byte) bool {
+	return isChunk(data) || bytes.HasPrefix(data, refreshPrefix) || isLockInfo(data)
+}
+
+// recomputeDelta returns the computation time recorded with SetDelta.
+func recomputeDelta(setOptions []SetOption) time.Duration {
+	var options setOption
+	for _, opt := range setOptions {
+		opt(&options)
+	}
+
+	if options.Delta == nil {
+		return 0
+	}
+	return *options.Delta
+}
@@ -0,0 +1,98 @@
+package rv
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/redis/rueidis"
+	rueidismock "github.com/redis/rueidis/mock"
+	"go.uber.org/mock/gomock"
+)
+
+func TestValueFetchCachesComputedValue(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	value := NewValue[testPayload](nil, nil, "report", WithStore(NewMemoryStore()), WithDefaultExpiration(time.Minute))
+
+	computed := 0
+	recompute := func(context.Context) (*testPayload, error) {
+		computed++
+		return &testPayload{Message: "fresh"}, nil
+	}
+
+	for range 2 {
+		got, err := value.Fetch(ctx, "daily", recompute)
+		if err != nil || got.Message != "fresh" {
+			t.Fatalf("Fetch returned %v, %v", got, err)
+		}
+	}
+	if computed != 1 {
+		t.Fatalf("expected a single computation, got %d", computed)
+	}
+}
+
+func TestValueEarlyExpirationElectsSingleRefresher(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	store := NewMemoryStore()
+	// A huge beta makes every read within the TTL eligible for early expiration.
+	value := NewValue[testPayload](nil, nil, "report", WithStore(store), WithEarlyExpiration(1e12))
+
+	if err := value.Set(ctx, "daily", &testPayload{Message: "cached"}, SetTTL(time.Minute), SetDelta(time.Millisecond)); err != nil {
+		t.Fatalf("Set returned error: %v", err)
+	}
+
+	if _, err := value.Get(ctx, "daily"); !errors.Is(err, ErrEarlyExpiration) || !errors.Is(err, rueidis.Nil) {
+		t.Fatalf("expected the first reader to refresh early, got %v", err)
+	}
+
+	got, err := value.Get(ctx, "daily")
+	if err != nil || got.Message != "cached" {
+		t.Fatalf("expected other readers to keep the cached value, got %v, %v", got, err)
+	}
+
+	values, err := value.Scan(ctx, "")
+	if err != nil || len(values) != 1 {
+		t.Fatalf("expected Scan to skip the refresh claim, got %v, %v", values, err)
+	}
+
+	if err := value.Set(ctx, "plain", &testPayload{Message: "no delta"}, SetTTL(time.Minute)); err != nil {
+		t.Fatalf("Set returned error: %v", err)
+	}
+	if _, err := value.Get(ctx, "plain"); err != nil {
+		t.Fatalf("expected values without a delta to expire normally, got %v", err)
+	}
+}
+
+func TestValueEarlyExpirationReadsTTLWithValue(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	client := rueidismock.NewClient(ctrl)
+	value := NewValue[testPayload](client, nil, "report", WithEarlyExpiration(1))
+
+	entry, err := value.encodeEntry(&testPayload{Message: "cached"}, time.Millisecond)
+	if err != nil {
+		t.Fatalf("failed to encode entry: %v", err)
+	}
+
+	// A minute of TTL left makes refreshing a value computed in a millisecond all but impossible.
+	client.EXPECT().
+		DoMulti(ctx, matchGetCommand("report:daily"), rueidismock.Match("PTTL", "report:daily")).
+		Return([]rueidis.RedisResult{
+			rueidismock.Result(rueidismock.RedisBlobString(string(entry))),
+			rueidismock.Result(rueidismock.RedisInt64(time.Minute.Milliseconds())),
+		})
+
+	got, err := value.Get(ctx, "daily")
+	if err != nil || got.Message != "cached" {
+		t.Fatalf("expected the cached value, got %v, %v", got, err)
+	}
+}